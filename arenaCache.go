@@ -0,0 +1,286 @@
+package ctx_cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var _ Cache = &ArenaCache{}
+var _ BatchCache = &ArenaCache{}
+
+// arenaHeaderSize is the size of the header written before every entry: total size, key
+// hash, expiry in unix nanoseconds and key length.
+const arenaHeaderSize = 4 + 8 + 8 + 2
+
+// ArenaCache keeps values in preallocated byte rings, one per shard, indexed by maps from
+// key hash to ring offset. Neither holds pointers, so the garbage collector does not scan
+// the cached data and memory stays at the configured size. Once a ring is full the oldest
+// entries are overwritten and counted with the EVICT command. Keys of up to 64KB are
+// supported.
+type ArenaCache struct {
+	defaultDuration time.Duration
+	shards          []*arenaShard
+	mask            uint64
+	cacheTags       CacheTags
+}
+
+type arenaShard struct {
+	mu    sync.RWMutex
+	index map[uint64]uint32
+	buf   []byte
+	// head is the offset of the oldest entry and tail the offset the next one is written
+	// at, entries holds the number of entries between them including replaced ones.
+	head, tail int
+	entries    int
+}
+
+func ArenaCacheFlags(prefix string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(prefix+"arenacache", pflag.ExitOnError)
+	fs.Int64(prefix+"arenacache-max-bytes", 256<<20, "")
+	fs.Int(prefix+"arenacache-shards", 0, "0 for 4 shards per CPU")
+	fs.Duration(prefix+"arenacache-default-duration", 5*time.Minute, "")
+
+	return fs
+}
+
+func NewArenaCacheFromFlags(prefix string) *ArenaCache {
+	return NewArenaCache(viper.GetInt64(prefix+"arenacache-max-bytes"), viper.GetInt(prefix+"arenacache-shards"), viper.GetDuration(prefix+"arenacache-default-duration"), prefix)
+}
+
+// NewArenaCache allocates maxBytes split across shards rounded up to a power of two, 4 per
+// CPU when shards is 0. A single entry can use at most the size of one shard.
+func NewArenaCache(maxBytes int64, shards int, defaultDuration time.Duration, instance string) *ArenaCache {
+	if shards <= 0 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	shards = 1 << bits.Len(uint(shards-1))
+	shardBytes := min(maxBytes/int64(shards), 1<<32-1)
+	c := &ArenaCache{
+		defaultDuration: defaultDuration,
+		shards:          make([]*arenaShard, shards),
+		mask:            uint64(shards - 1),
+		cacheTags:       NewCacheTags("arena-cache", instance),
+	}
+	for i := range c.shards {
+		c.shards[i] = &arenaShard{
+			index: map[uint64]uint32{},
+			buf:   make([]byte, shardBytes),
+		}
+	}
+	return c
+}
+
+func (c *ArenaCache) GetName() string {
+	return fmt.Sprintf("ARENACACHE_%s", c.cacheTags.instance)
+}
+
+func (c *ArenaCache) GetParentCaches() map[string]Cache {
+	return map[string]Cache{}
+}
+
+func (c *ArenaCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (c *ArenaCache) Close() {
+
+}
+
+// Len returns the number of stored keys, including expired ones not yet overwritten.
+func (c *ArenaCache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.RLock()
+		n += len(s.index)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+func (c *ArenaCache) shard(hash uint64) *arenaShard {
+	return c.shards[hash&c.mask]
+}
+
+func (c *ArenaCache) DeleteKey(ctx context.Context, key string) error {
+	h := xxhash.Sum64String(key)
+	s := c.shard(h)
+	s.mu.Lock()
+	s.delete(h, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (c *ArenaCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	data, err := encodeItem(item, cacheTimeout)
+	if err != nil {
+		return err
+	}
+	h := xxhash.Sum64String(key)
+	s := c.shard(h)
+	s.mu.Lock()
+	evicted, err := s.set(h, key, data, cacheTimeout)
+	s.mu.Unlock()
+	for i := 0; i < evicted; i++ {
+		c.cacheTags.record(ctx, CacheCmdEVICT, func(err error) CacheStatus {
+			return CacheStatusOK
+		})(nil)
+	}
+	return err
+}
+
+func (c *ArenaCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return c.SetCacheWithExpiration(ctx, c.defaultDuration, group, key, item)
+}
+
+func (c *ArenaCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	h := xxhash.Sum64String(key)
+	s := c.shard(h)
+	s.mu.RLock()
+	data, ok := s.get(h, key, time.Now().UnixNano())
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return data, nil
+}
+
+func (c *ArenaCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	found := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if data, err := c.GetCache(ctx, group, key); err == nil {
+			found[key] = data
+		}
+	}
+	return found, nil
+}
+
+func (c *ArenaCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	for key, item := range items {
+		if err := c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ArenaCache) DeleteKeys(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		_ = c.DeleteKey(ctx, key)
+	}
+	return nil
+}
+
+// lookup returns the offset of the entry stored for key. s.mu must be held.
+func (s *arenaShard) lookup(h uint64, key string) (int, bool) {
+	off, ok := s.index[h]
+	if !ok {
+		return 0, false
+	}
+	o := int(off)
+	keyLen := int(binary.LittleEndian.Uint16(s.buf[o+20:]))
+	if !bytes.Equal(s.buf[o+arenaHeaderSize:o+arenaHeaderSize+keyLen], []byte(key)) {
+		return 0, false
+	}
+	return o, true
+}
+
+// get returns a copy of the value stored for key. s.mu must be held for reading.
+func (s *arenaShard) get(h uint64, key string, now int64) ([]byte, bool) {
+	o, ok := s.lookup(h, key)
+	if !ok {
+		return nil, false
+	}
+	size := int(binary.LittleEndian.Uint32(s.buf[o:]))
+	if expiresAt := int64(binary.LittleEndian.Uint64(s.buf[o+12:])); expiresAt > 0 && now > expiresAt {
+		return nil, false
+	}
+	keyLen := int(binary.LittleEndian.Uint16(s.buf[o+20:]))
+	return bytes.Clone(s.buf[o+arenaHeaderSize+keyLen : o+size]), true
+}
+
+// delete removes key from the index, its bytes are reclaimed when the ring wraps over
+// them. s.mu must be held.
+func (s *arenaShard) delete(h uint64, key string) {
+	if _, ok := s.lookup(h, key); ok {
+		delete(s.index, h)
+	}
+}
+
+// set appends an entry for key at the tail of the ring, overwriting the oldest entries
+// until it fits, and returns how many live entries were evicted. s.mu must be held.
+func (s *arenaShard) set(h uint64, key string, data []byte, cacheTimeout time.Duration) (int, error) {
+	size := arenaHeaderSize + len(key) + len(data)
+	if len(key) > 1<<16-1 || size > len(s.buf) {
+		s.delete(h, key)
+		return 0, fmt.Errorf("cache entry of %d bytes does not fit in an arena shard of %d bytes", size, len(s.buf))
+	}
+	evicted := 0
+	for !s.fits(size) {
+		if s.evictOldest() {
+			evicted++
+		}
+	}
+	if len(s.buf)-s.tail < size {
+		if len(s.buf)-s.tail >= 4 {
+			binary.LittleEndian.PutUint32(s.buf[s.tail:], 0)
+		}
+		s.tail = 0
+	}
+	var expiresAt int64
+	if cacheTimeout > 0 {
+		expiresAt = time.Now().Add(cacheTimeout).UnixNano()
+	}
+	o := s.tail
+	binary.LittleEndian.PutUint32(s.buf[o:], uint32(size))
+	binary.LittleEndian.PutUint64(s.buf[o+4:], h)
+	binary.LittleEndian.PutUint64(s.buf[o+12:], uint64(expiresAt))
+	binary.LittleEndian.PutUint16(s.buf[o+20:], uint16(len(key)))
+	copy(s.buf[o+arenaHeaderSize:], key)
+	copy(s.buf[o+arenaHeaderSize+len(key):], data)
+	s.index[h] = uint32(o)
+	s.tail += size
+	s.entries++
+	return evicted, nil
+}
+
+// fits reports whether size bytes can be written at the tail, or at the start of the ring
+// when the tail has no room left.
+func (s *arenaShard) fits(size int) bool {
+	if s.entries == 0 {
+		s.head, s.tail = 0, 0
+		return true
+	}
+	if s.tail <= s.head {
+		return s.head-s.tail >= size
+	}
+	return len(s.buf)-s.tail >= size || s.head >= size
+}
+
+// evictOldest drops the entry at the head of the ring and reports whether it was still
+// indexed.
+func (s *arenaShard) evictOldest() bool {
+	if len(s.buf)-s.head < 4 || binary.LittleEndian.Uint32(s.buf[s.head:]) == 0 {
+		s.head = 0
+	}
+	o := s.head
+	size := int(binary.LittleEndian.Uint32(s.buf[o:]))
+	h := binary.LittleEndian.Uint64(s.buf[o+4:])
+	live := false
+	if off, ok := s.index[h]; ok && int(off) == o {
+		delete(s.index, h)
+		live = true
+	}
+	s.head += size
+	s.entries--
+	return live
+}
@@ -0,0 +1,63 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestArenaCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewArenaCache(4096, 2, time.Minute, "arena")
+
+	_ = c.SetCache(ctx, "", "a", []byte("1"))
+	_ = c.SetCache(ctx, "", "a", []byte("2"))
+	if v, err := c.GetCache(ctx, "", "a"); err != nil || string(v) != "2" {
+		t.Fatalf("expected latest value, got %q (%v)", v, err)
+	}
+	_ = c.DeleteKey(ctx, "a")
+	if _, err := c.GetCache(ctx, "", "a"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected deleted key to miss, got %v", err)
+	}
+	if err := c.SetCache(ctx, "", "big", make([]byte, 4096)); err == nil {
+		t.Fatalf("expected value larger than a shard to be rejected")
+	}
+	_ = c.SetCacheWithExpiration(ctx, time.Millisecond, "", "short", []byte("v"))
+	time.Sleep(5 * time.Millisecond)
+	if _, err := c.GetCache(ctx, "", "short"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected expired key to miss, got %v", err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	latest := map[string]string{}
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprint("k", r.Intn(200))
+		value := fmt.Sprint(i, string(make([]byte, r.Intn(100))))
+		if err := c.SetCache(ctx, "", key, []byte(value)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		latest[key] = value
+	}
+	found := 0
+	for key, value := range latest {
+		v, err := c.GetCache(ctx, "", key)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		}
+		if err != nil || string(v) != value {
+			t.Fatalf("expected %s to be %q or evicted, got %q (%v)", key, value, v, err)
+		}
+		found++
+	}
+	if found == 0 || found != c.Len() {
+		t.Fatalf("expected the most recent values to be kept, found %d of %d indexed", found, c.Len())
+	}
+	for _, s := range c.shards {
+		if len(s.buf) != 2048 {
+			t.Fatalf("expected shard size to stay at 2048 bytes, got %d", len(s.buf))
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// BatchCache is implemented by caches that can read, write and delete many keys in a
+// single round trip. Caches without it are driven one key at a time.
+type BatchCache interface {
+	// GetCacheMany returns the stored bytes for every key that was found, misses are left
+	// out of the result.
+	GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error)
+	SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error
+	DeleteKeys(ctx context.Context, keys []string) error
+}
+
+// GetMany reads keys from group in one batch. Values are returned by key, keys that were
+// missing, expired or could not be decoded are returned as misses.
+func GetMany[T any](ctx context.Context, group string, keys []string) (map[string]*T, []string, error) {
+	group = policyGroup[T](group, "")
+	c := GetCacheFromContext(ctx)
+	cacheKeys := make([]string, len(keys))
+	found := make(map[string]*T, len(keys))
+	// objects are returned as they are, only the other keys are read as bytes
+	var byteKeys []string
+	now := time.Now()
+	for i, key := range keys {
+		cacheKeys[i] = cacheKey[T](ctx, group, key)
+		v, meta, ok, err := lookupObject[T](c, cacheKeys[i])
+		if !ok {
+			byteKeys = append(byteKeys, cacheKeys[i])
+		} else if err == nil && !meta.IsExpired(now) {
+			found[key] = v
+		}
+	}
+	var data map[string][]byte
+	if len(byteKeys) > 0 {
+		var err error
+		if data, err = getCacheMany(ctx, c, group, byteKeys); err != nil {
+			return nil, nil, err
+		}
+	}
+	codec := codecFor[T](ctx, group)
+	var misses []string
+	for i, key := range keys {
+		if _, ok := found[key]; ok {
+			continue
+		}
+		d, ok := data[cacheKeys[i]]
+		if !ok {
+			misses = append(misses, key)
+			continue
+		}
+		if checkIntegrity(ctx, cacheKeys[i], d) != nil {
+			misses = append(misses, key)
+			continue
+		}
+		v, _, err := decodeValue[T](codec, d)
+		recordSchemaMismatch(ctx, err)
+		if err != nil || v == nil {
+			misses = append(misses, key)
+			continue
+		}
+		found[key] = v
+	}
+	return found, misses, nil
+}
+
+// SetMany writes every item to group with cacheTimeout in one batch.
+func SetMany[T any](ctx context.Context, cacheTimeout time.Duration, group string, items map[string]T) error {
+	group = policyGroup[T](group, "")
+	cacheTimeout = policyTTL[T](cacheTimeout)
+	return setMany[T](ctx, newEntryMeta(cacheTimeout), cacheTimeout, group, items)
+}
+
+// GetSetMany reads keys from group in one batch and calls loader once with only the keys
+// that missed. Loaded values are written back like GetSet and merged into the result, keys
+// the loader does not return are left out. When the loader fails the cached values are
+// returned with its error.
+func GetSetMany[T any](ctx context.Context, cacheTimeout time.Duration, group string, keys []string, loader func(ctx context.Context, missing []string) (map[string]T, error)) (map[string]T, error) {
+	group = policyGroup[T](group, "")
+	cacheTimeout = policyTTL[T](cacheTimeout)
+	found, misses, err := GetMany[T](ctx, group, keys)
+	if err != nil {
+		found, misses = nil, keys
+	}
+	out := make(map[string]T, len(keys))
+	for key, v := range found {
+		out[key] = *v
+	}
+	if len(misses) == 0 {
+		return out, nil
+	}
+	start := time.Now()
+	loaded, err := loader(ctx, misses)
+	if err != nil {
+		return out, err
+	}
+	for key, v := range loaded {
+		out[key] = v
+	}
+	return out, setMany[T](ctx, loadedMeta(ctx, cacheTimeout, time.Since(start)), cacheTimeout, group, loaded)
+}
+
+// setMany writes items with the deadlines recorded in meta and registers them with the
+// group monitor.
+func setMany[T any](ctx context.Context, meta entryMeta, cacheTimeout time.Duration, group string, items map[string]T) error {
+	if len(items) == 0 {
+		return nil
+	}
+	encoded := make(map[string]interface{}, len(items))
+	for key, data := range items {
+		w, err := encodeValue[T](ctx, group, data, meta)
+		if err != nil {
+			return err
+		}
+		encoded[cacheKey[T](ctx, group, key)] = w
+	}
+	if err := setCacheMany(ctx, GetCacheFromContext(ctx), meta.StoreTimeout(cacheTimeout), group, encoded); err != nil {
+		return err
+	}
+	if strings.EqualFold(group, GroupPrefix) || group == "" {
+		return nil
+	}
+	var err error
+	for key := range items {
+		if group == key {
+			continue
+		}
+		err = multierr.Combine(err, GetMonitorFromContext(ctx).UpdateCache(ctx, group, cacheKey[T](ctx, group, key)))
+	}
+	return err
+}
+
+// DeleteMany removes keys from group in one batch.
+func DeleteMany[T any](ctx context.Context, group string, keys []string) error {
+	group = policyGroup[T](group, "")
+	cacheKeys := make([]string, len(keys))
+	for i, key := range keys {
+		cacheKeys[i] = cacheKey[T](ctx, group, key)
+	}
+	return deleteKeys(ctx, GetCacheFromContext(ctx), cacheKeys)
+}
+
+func getCacheMany(ctx context.Context, c GetCache, group string, keys []string) (map[string][]byte, error) {
+	if b, ok := c.(BatchCache); ok {
+		return b.GetCacheMany(ctx, group, keys)
+	}
+	found := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		v, err := c.GetCache(ctx, group, key)
+		if errors.Is(err, ErrCacheMiss) || (err == nil && v == nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[key] = v
+	}
+	return found, nil
+}
+
+func setCacheMany(ctx context.Context, c Cache, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	if b, ok := c.(BatchCache); ok {
+		return b.SetCacheManyWithExpiration(ctx, cacheTimeout, group, items)
+	}
+	var err error
+	for key, item := range items {
+		err = multierr.Combine(err, c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item))
+	}
+	return err
+}
+
+func deleteKeys(ctx context.Context, c Cache, keys []string) error {
+	if b, ok := c.(BatchCache); ok {
+		return b.DeleteKeys(ctx, keys)
+	}
+	var err error
+	for _, key := range keys {
+		err = multierr.Combine(err, c.DeleteKey(ctx, key))
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package ctx_cache
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestGetSetDeleteMany(t *testing.T) {
+	l1 := NewGoCache(cache.New(time.Minute, time.Minute), time.Hour, "batch_l1")
+	l2 := NewGoCache(cache.New(time.Minute, time.Minute), time.Hour, "batch_l2")
+	ctx := NewClient(NewTieredCache(nil, l1, l2)).Context(context.Background())
+
+	err := SetMany[string](ctx, time.Minute, "batch_group", map[string]string{"a": "1", "b": "2", "c": "3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := l1.DeleteKey(ctx, GetKey[string]("batch_group", "b")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found, misses, err := GetMany[string](ctx, "batch_group", []string{"a", "b", "c", "d"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 3 || *found["a"] != "1" || *found["b"] != "2" || *found["c"] != "3" {
+		t.Fatalf("unexpected values %v", found)
+	}
+	if len(misses) != 1 || misses[0] != "d" {
+		t.Fatalf("expected miss d, got %v", misses)
+	}
+
+	_, expiration, ok := l1.cacher.GetWithExpiration(GetKey[string]("batch_group", "b"))
+	if !ok {
+		t.Fatalf("expected l1 to be backfilled with b")
+	}
+	if time.Until(expiration) > time.Minute {
+		t.Fatalf("backfill extended the ttl to %s", time.Until(expiration))
+	}
+	if _, ok := l1.cacher.Get(GetKey[string]("batch_group", "d")); ok {
+		t.Fatalf("expected missing key not to be backfilled")
+	}
+
+	if err := DeleteMany[string](ctx, "batch_group", []string{"a", "c"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found, misses, err = GetMany[string](ctx, "batch_group", []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(misses)
+	if len(found) != 1 || len(misses) != 2 || misses[0] != "a" || misses[1] != "c" {
+		t.Fatalf("expected only b after delete, got %v misses %v", found, misses)
+	}
+}
+
+func TestGetSetMany(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "batch_loader")).Context(context.Background())
+
+	if err := Set[string](ctx, "loader_group", "a", "cached"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var requested [][]string
+	loader := func(ctx context.Context, missing []string) (map[string]string, error) {
+		requested = append(requested, missing)
+		out := map[string]string{}
+		for _, key := range missing {
+			if key != "missing" {
+				out[key] = "loaded_" + key
+			}
+		}
+		return out, nil
+	}
+
+	v, err := GetSetMany[string](ctx, time.Minute, "loader_group", []string{"a", "b", "c", "missing"}, loader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(v) != 3 || v["a"] != "cached" || v["b"] != "loaded_b" || v["c"] != "loaded_c" {
+		t.Fatalf("unexpected values %v", v)
+	}
+	if len(requested) != 1 || len(requested[0]) != 3 || requested[0][0] != "b" {
+		t.Fatalf("expected one loader call for the misses, got %v", requested)
+	}
+
+	v, err = GetSetMany[string](ctx, time.Minute, "loader_group", []string{"a", "b", "c"}, loader)
+	if err != nil || len(v) != 3 {
+		t.Fatalf("expected cached values, got %v (%v)", v, err)
+	}
+	if len(requested) != 1 {
+		t.Fatalf("expected loaded values to be cached, got %d loader calls", len(requested))
+	}
+}
@@ -10,8 +10,6 @@ import (
 	"runtime/trace"
 	"strings"
 	"time"
-
-	"github.com/patrickmn/go-cache"
 )
 
 const (
@@ -22,10 +20,13 @@ var (
 	ErrCacheMiss    = errors.New("cache missed")
 	ErrCacheUpdated = errors.New("cache updated")
 	ErrCacheGet     = errors.New("cache get")
-	DefaultCache    Cache
-	UseHash         bool = false
+	// DefaultCache and UseHash configure DefaultClient and are read once, when it is built.
+	DefaultCache Cache
+	UseHash      bool = false
 )
 
+// CacheObject is implemented by types that name the group they are cached in. Calls that
+// pass an empty group use it unless a Policy for the type sets Group.
 type CacheObject interface {
 	GetName() string
 }
@@ -54,25 +55,41 @@ func GetMD5Hash(text string) string {
 	return base64.StdEncoding.EncodeToString(hash[:])
 }
 
+// GetKey returns the backend key a T stored under group key1 and key key2 is kept under
+// by helpers called without a Client, using the key strategy of DefaultClient and the key
+// prefix of T's Policy. Clients configured with another KeyStrategy store it elsewhere.
 func GetKey[T any](key1, key2 string) string {
-	return GetTypeReflect[T]() + key1 + key2
-
+	return DefaultClient().keys(GetTypeReflect[T](), key1, policyKey[T](key2))
 }
 
 func Set[T any](ctx context.Context, group, key string, data T) error {
-	k := GetKey[T](group, key)
-	err := GetCacheFromContext(ctx).SetCache(ctx, group, k, Wrapper[T]{Data: data}.Get())
-	if err != nil {
-		return err
+	group = policyGroup[T](group, key)
+	if cacheTimeout := policyTTL[T](0); cacheTimeout > 0 {
+		return setEntry[T](ctx, newEntryMeta(cacheTimeout), cacheTimeout, group, key, data)
 	}
-	if strings.EqualFold(group, GroupPrefix) || group == "" || group == key {
-		return nil
+	k := cacheKey[T](ctx, group, key)
+	oc, isObject := GetCacheFromContext(ctx).(objectStore)
+	if !isObject || !oc.objectOnly() {
+		v, err := encodeValue[T](ctx, group, data, newEntryMeta(0))
+		if err != nil {
+			return err
+		}
+		err = GetCacheFromContext(ctx).SetCache(ctx, group, k, v)
+		if err != nil {
+			return err
+		}
 	}
-	return GlobalCacheMonitor.UpdateCache(ctx, group, k)
+	if isObject {
+		meta := newEntryMeta(0)
+		meta.TypeHash = typeHash[T]()
+		oc.setObject(k, data, meta, 0)
+	}
+	return recordGroupKey(ctx, group, key, k)
 }
 
 func Delete[T any](ctx context.Context, group, key string) error {
-	return GetCacheFromContext(ctx).DeleteKey(ctx, GetKey[T](group, key))
+	group = policyGroup[T](group, key)
+	return GetCacheFromContext(ctx).DeleteKey(ctx, cacheKey[T](ctx, group, key))
 }
 
 func DeleteKey(ctx context.Context, key string) error {
@@ -80,33 +97,81 @@ func DeleteKey(ctx context.Context, key string) error {
 }
 
 func SetWithExpiration[T any](ctx context.Context, cacheTimeout time.Duration, group, key string, data T) error {
+	group = policyGroup[T](group, key)
+	cacheTimeout = policyTTL[T](cacheTimeout)
+	return setEntry[T](ctx, newEntryMeta(cacheTimeout), cacheTimeout, group, key, data)
+}
+
+// setEntry writes data with the deadlines recorded in meta, the backend keeps it for
+// cacheTimeout plus any stale-if-error grace window.
+func setEntry[T any](ctx context.Context, meta entryMeta, cacheTimeout time.Duration, group, key string, data T) error {
 	getSetKey := trace.StartRegion(ctx, "get_set_key")
-	c := GetCacheFromContext(ctx)
-	k := GetKey[T](group, key)
+	k := cacheKey[T](ctx, group, key)
 	getSetKey.End()
+	oc, isObject := GetCacheFromContext(ctx).(objectStore)
+	if isObject && oc.objectOnly() {
+		meta.TypeHash = typeHash[T]()
+		oc.setObject(k, data, meta, meta.StoreTimeout(cacheTimeout))
+		return recordGroupKey(ctx, group, key, k)
+	}
 	encodedData := trace.StartRegion(ctx, "encodeData")
-
-	w := Wrapper[T]{Data: data}.Get()
+	w, err := encodeValue[T](ctx, group, data, meta)
 	encodedData.End()
+	if err != nil {
+		return err
+	}
+	if err := storeEntry(ctx, meta.StoreTimeout(cacheTimeout), group, key, k, w); err != nil || !isObject {
+		return err
+	}
+	meta.TypeHash = typeHash[T]()
+	oc.setObject(k, data, meta, meta.StoreTimeout(cacheTimeout))
+	return nil
+}
+
+// storeEntry writes encoded bytes under the full cache key k and records k in the group.
+func storeEntry(ctx context.Context, cacheTimeout time.Duration, group, key, k string, w []byte) error {
 	setCache := trace.StartRegion(ctx, "setCache")
-	err := c.SetCacheWithExpiration(ctx, cacheTimeout, group, k, w)
+	err := GetCacheFromContext(ctx).SetCacheWithExpiration(ctx, cacheTimeout, group, k, w)
 	setCache.End()
 	if err != nil {
 		return err
 	}
+	return recordGroupKey(ctx, group, key, k)
+}
+
+// recordGroupKey records the full cache key k of group/key with the monitor in ctx.
+func recordGroupKey(ctx context.Context, group, key, k string) error {
 	if strings.EqualFold(group, GroupPrefix) || group == "" || group == key {
 		return nil
 	}
 	updateGlobal := trace.StartRegion(ctx, "update_global")
 	defer updateGlobal.End()
-	return GlobalCacheMonitor.UpdateCache(ctx, group, k)
+	return GetMonitorFromContext(ctx).UpdateCache(ctx, group, k)
 }
 
 func SetFromCache[T any](ctx context.Context, cache Cache, group, key string, data T) error {
-	return cache.SetCache(ctx, group, GetKey[T](group, key), Wrapper[T]{Data: data}.Get())
+	group = policyGroup[T](group, key)
+	if cacheTimeout := policyTTL[T](0); cacheTimeout > 0 {
+		return setFromCache[T](ctx, cache, cacheTimeout, group, key, data)
+	}
+	v, err := encodeValue[T](ctx, group, data, newEntryMeta(0))
+	if err != nil {
+		return err
+	}
+	return cache.SetCache(ctx, group, cacheKey[T](ctx, group, key), v)
 }
 func SetFromCacheWithExpiration[T any](ctx context.Context, cache Cache, cacheTimeout time.Duration, group, key string, data T) error {
-	return cache.SetCacheWithExpiration(ctx, cacheTimeout, group, GetKey[T](group, key), Wrapper[T]{Data: data}.Get())
+	group = policyGroup[T](group, key)
+	return setFromCache[T](ctx, cache, policyTTL[T](cacheTimeout), group, key, data)
+}
+
+// setFromCache writes data to cache for cacheTimeout, with the Policy of T already applied.
+func setFromCache[T any](ctx context.Context, cache Cache, cacheTimeout time.Duration, group, key string, data T) error {
+	v, err := encodeValue[T](ctx, group, data, newEntryMeta(cacheTimeout))
+	if err != nil {
+		return err
+	}
+	return cache.SetCacheWithExpiration(ctx, cacheTimeout, group, cacheKey[T](ctx, group, key), v)
 }
 
 type Wrapper[T any] struct {
@@ -132,7 +197,110 @@ func UnmarshalWrappert[T any](data []byte) (*T, error) {
 	return &output.Data, nil
 }
 
+// encodeValue encodes data behind the entry header with the codec and compression the
+// client in ctx uses for group, a nil codec uses the built-in encoding.
+func encodeValue[T any](ctx context.Context, group string, data T, meta entryMeta) ([]byte, error) {
+	codec := codecFor[T](ctx, group)
+	var payload []byte
+	var err error
+	if codec != nil {
+		payload, err = codec.Marshal(&data)
+	} else {
+		payload, err = ConvertToBytes(Wrapper[T]{Data: data}.Get())
+	}
+	if err != nil {
+		return nil, err
+	}
+	decoded := len(payload)
+	payload, meta.Compression, err = compressPayload(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	recordPayload(ctx, CacheCmdSET, CacheStatusOK, len(payload), decoded)
+	meta = meta.withIntegrity(ctx)
+	meta.Codec = codecID(codec)
+	meta.TypeHash = typeHash[T]()
+	return meta.Encode(payload), nil
+}
+
+// decodeValue reads the entry header and payload, entries past their hard deadline are
+// reported as a cache miss even if the backend has not evicted them yet.
+func decodeValue[T any](codec Codec, data []byte) (*T, entryMeta, error) {
+	v, meta, err := decodeStoredValue[T](codec, data)
+	if err != nil {
+		return nil, meta, err
+	}
+	if meta.IsExpired(time.Now()) {
+		return nil, meta, ErrCacheMiss
+	}
+	return v, meta, nil
+}
+
+// decodeStoredValue decodes the payload without checking the entry deadlines. Tombstones
+// return their cached negative error, or a miss once expired, and entries written with
+// another codec or for another type read as a miss. Entries written with another layout
+// of a type whose Policy sets Schema return errSchemaMismatch.
+func decodeStoredValue[T any](codec Codec, data []byte) (*T, entryMeta, error) {
+	meta, payload, err := decodeEntryMeta(data)
+	if err != nil {
+		return nil, meta, err
+	}
+	if meta.Tombstone {
+		return nil, meta, tombstoneError(meta, payload)
+	}
+	if meta.Codec != codecID(codec) {
+		return nil, meta, ErrCacheMiss
+	}
+	if hash := typeHash[T](); meta.TypeHash != hash {
+		if p, ok := policyFor[T](); ok && p.Schema {
+			return nil, meta, errSchemaMismatch
+		}
+		if meta.TypeHash != 0 {
+			return nil, meta, ErrCacheMiss
+		}
+	}
+	payload, err = decompressPayload(meta.Compression, payload)
+	if err != nil {
+		return nil, meta, err
+	}
+	meta.decodedSize = len(payload)
+	if codec != nil {
+		v := new(T)
+		if err := codec.Unmarshal(payload, v); err != nil {
+			return nil, meta, err
+		}
+		return v, meta, nil
+	}
+	if CheckPrimaryType[T](*new(T)) {
+		t, err := ConvertBytesToType[T](payload)
+		if err != nil {
+			return nil, meta, err
+		}
+		return &t, meta, nil
+	}
+	v, err := UnmarshalWrappert[T](payload)
+	return v, meta, err
+}
+
 func Get[T any](ctx context.Context, group, key string) (*T, error) {
+	group = policyGroup[T](group, key)
+	v, _, err := getEntry[T](ctx, group, key)
+	return v, err
+}
+
+func getEntry[T any](ctx context.Context, group, key string) (*T, entryMeta, error) {
+	v, meta, err := lookupEntry[T](ctx, group, key)
+	if err != nil {
+		return nil, meta, err
+	}
+	if meta.IsExpired(time.Now()) {
+		return nil, meta, ErrCacheMiss
+	}
+	return v, meta, nil
+}
+
+// lookupEntry reads group/key without checking the entry deadlines.
+func lookupEntry[T any](ctx context.Context, group, key string) (*T, entryMeta, error) {
 	//if group != GroupPrefix && group != "" {
 	//
 	//	if GlobalCacheMonitor.HasGroupKeyBeenUpdated(ctx, group) {
@@ -140,179 +308,88 @@ func Get[T any](ctx context.Context, group, key string) (*T, error) {
 	//	}
 	//}
 	getKey := trace.StartRegion(ctx, "get_key")
-	key = GetKey[T](group, key)
+	key = cacheKey[T](ctx, group, key)
 	c := GetCacheFromContext(ctx)
 	getKey.End()
+	if v, meta, found, err := lookupObject[T](c, key); found {
+		return v, meta, err
+	}
 	getCache := trace.StartRegion(ctx, "get_cache")
 	data, err := c.GetCache(ctx, group, key)
 	getCache.End()
 	if err != nil {
-		return nil, err
+		return nil, entryMeta{}, err
+	}
+	if err := checkIntegrity(ctx, key, data); err != nil {
+		return nil, entryMeta{}, err
 	}
 
 	convert := trace.StartRegion(ctx, "convert")
 	defer convert.End()
-	if CheckPrimaryType[T](*new(T)) {
-		t, err := ConvertBytesToType[T](data)
-		if err != nil {
-			return nil, err
-		}
-		return &t, nil
+	v, meta, err := decodeStoredValue[T](codecFor[T](ctx, group), data)
+	status := CacheStatusFOUND
+	if err != nil {
+		status = CacheStatusMISSING
+		recordSchemaMismatch(ctx, err)
 	}
-
-	return UnmarshalWrappert[T](data)
-	//var output Wrapper[T]
-	//err = json.Unmarshal(data, &output)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//return &output.Data, nil
+	recordPayload(ctx, CacheCmdGET, status, len(data), meta.decodedSize)
+	return v, meta, err
 }
 
 func GetSet[T any](ctx context.Context, cacheTimeout time.Duration, group, key string, refresh bool, gtr func(ctx context.Context) (T, error)) (T, error) {
-	if refresh {
-		nv, err := gtr(ctx)
-		if err != nil {
-			var tmp T
-			return tmp, err
-		}
-		return nv, SetWithExpiration[T](ctx, cacheTimeout, group, key, nv)
-	}
-	if v, err := Get[T](ctx, group, key); errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrCacheUpdated) || v == nil {
-		nv, err := gtr(ctx)
-		if err != nil {
-			var tmp T
-			return tmp, err
-		}
-		return nv, SetWithExpiration[T](ctx, cacheTimeout, group, key, nv)
-	} else {
-		return *v, nil
-	}
+	return fetchValue(Fetch[T](ctx, group, key, valueLoader(gtr), WithTTL(cacheTimeout), WithRefresh(refresh), withRawErrors()))
 }
 
 func GetSetP[T any](ctx context.Context, cacheTimeout time.Duration, group, key string, refresh bool, gtr func(ctx context.Context) (*T, error)) (*T, error) {
-	if refresh {
-		nv, err := gtr(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed getting cache value(group:%s, key:%s): %w", group, key, err)
-		}
-		if nv == nil {
-			return nil, ErrCacheGet
-		}
-		return nv, SetWithExpiration[T](ctx, cacheTimeout, group, key, *nv)
-	}
-	if v, err := Get[T](ctx, group, key); errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrCacheUpdated) || v == nil {
-		nv, err := gtr(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed getting cache value(group:%s, key:%s): %w", group, key, err)
-		}
-		if nv == nil {
-			return nil, ErrCacheGet
-		}
-		return nv, SetWithExpiration[T](ctx, cacheTimeout, group, key, *nv)
-	} else {
-		return v, nil
-	}
+	return Fetch[T](ctx, group, key, gtr, WithTTL(cacheTimeout), WithRefresh(refresh))
 }
 
 func GetSetCheck[T any](ctx context.Context, cacheTimeout time.Duration, group, key string, refresh bool, isValid func(ctx context.Context, data *T) bool, gtr func(ctx context.Context) (T, error)) (T, error) {
-	if refresh {
-		nv, err := gtr(ctx)
-		if err != nil {
-			var tmp T
-			return tmp, err
-		}
-		return nv, SetWithExpiration[T](ctx, cacheTimeout, group, key, nv)
-	}
-	if v, err := Get[T](ctx, group, key); errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrCacheUpdated) || v == nil || !isValid(ctx, v) {
-		nv, err := gtr(ctx)
-		if err != nil {
-			var tmp T
-			return tmp, err
-		}
-		return nv, SetWithExpiration[T](ctx, cacheTimeout, group, key, nv)
-	} else {
-		return *v, nil
-	}
+	return fetchValue(Fetch[T](ctx, group, key, valueLoader(gtr), WithTTL(cacheTimeout), WithRefresh(refresh), WithCheck(isValid), withRawErrors()))
 }
 
 func GetSetCheckP[T any](ctx context.Context, cacheTimeout time.Duration, group, key string, refresh bool, isValid func(ctx context.Context, data *T) bool, gtr func(ctx context.Context) (*T, error)) (*T, error) {
-	if refresh {
-		refresh := trace.StartRegion(ctx, "refresh_function")
-		nv, err := gtr(ctx)
-		refresh.End()
-		if err != nil {
-			return nil, fmt.Errorf("failed getting cache value(group:%s, key:%s): %w", group, key, err)
-		}
-		if nv == nil {
-			return nil, ErrCacheGet
-		}
-		setWithExpiration := trace.StartRegion(ctx, "set_with_expiration")
-		defer setWithExpiration.End()
-		return nv, SetWithExpiration[T](ctx, cacheTimeout, group, key, *nv)
-	}
-	get := trace.StartRegion(ctx, "get")
-	v, err := Get[T](ctx, group, key)
-	get.End()
-	if errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrCacheUpdated) || v == nil || !isValid(ctx, v) {
-		setFunctionCall := trace.StartRegion(ctx, "set_function_call")
-		nv, err := gtr(ctx)
-		setFunctionCall.End()
-		if err != nil {
-			return nil, fmt.Errorf("failed getting cache value(group:%s, key:%s): %w", group, key, err)
-		}
-		if nv == nil {
-			return nil, ErrCacheGet
-		}
-		setWithExpiration := trace.StartRegion(ctx, "set_with_expiration")
-		defer setWithExpiration.End()
-		return nv, SetWithExpiration[T](ctx, cacheTimeout, group, key, *nv)
-	} else {
-		return v, nil
-	}
+	return Fetch[T](ctx, group, key, gtr, WithTTL(cacheTimeout), WithRefresh(refresh), WithCheck(isValid))
 }
 
 func GetFromCache[T any](ctx context.Context, cache Cache, group, key string) (*T, error) {
-	if GlobalCacheMonitor.HasGroupKeyBeenUpdated(ctx, group) {
+	group = policyGroup[T](group, key)
+	if GetMonitorFromContext(ctx).HasGroupKeyBeenUpdated(ctx, group) {
 		return nil, ErrCacheUpdated
 	}
-	data, err := cache.GetCache(ctx, group, GetKey[T](group, key))
+	k := cacheKey[T](ctx, group, key)
+	if v, meta, found, err := lookupObject[T](cache, k); found {
+		if err == nil && meta.IsExpired(time.Now()) {
+			return nil, ErrCacheMiss
+		}
+		return v, err
+	}
+	data, err := cache.GetCache(ctx, group, k)
 	if err != nil {
 		return nil, err
 	}
-	var output Wrapper[T]
-	err = json.Unmarshal(data, &output)
-	if err != nil {
+	if err := checkIntegrity(ContextWithCache(ctx, cache), k, data); err != nil {
 		return nil, err
 	}
-	return &output.Data, nil
+	v, _, err := decodeValue[T](codecFor[T](ctx, group), data)
+	recordSchemaMismatch(ctx, err)
+	return v, err
 }
 
 func ContextWithCache(ctx context.Context, cache Cache) context.Context {
 	return context.WithValue(ctx, CTX_CACHE, cache) //nolint:staticcheck
 }
 
+// GetCacheFromContext returns the cache set with ContextWithCache, falling back to the
+// cache of the client in ctx or of DefaultClient.
 func GetCacheFromContext(ctx context.Context) Cache {
-	if ctx == nil {
-		if DefaultCache == nil {
-			DefaultCache = &GoCache{
-				defaultDuration: cache.DefaultExpiration,
-				cacher:          cache.New(5*time.Minute, time.Minute),
-				cacheTags:       NewCacheTags("go-cache", "backup"),
-			}
-		}
-		return DefaultCache
-	}
-	gCache := ctx.Value(CTX_CACHE)
-	if gCache == nil {
-		if DefaultCache == nil {
-			DefaultCache = &GoCache{
-				defaultDuration: cache.DefaultExpiration,
-				cacher:          cache.New(5*time.Minute, time.Minute),
-				cacheTags:       NewCacheTags("go-cache", "backup"),
-			}
+	if ctx != nil {
+		if c, ok := ctx.Value(CTX_CACHE).(Cache); ok && c != nil {
+			return c
 		}
-		return DefaultCache
 	}
-	return gCache.(Cache)
+	if c := clientFromContext(ctx).cache; c != nil {
+		return c
+	}
+	return DefaultClient().cache
 }
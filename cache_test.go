@@ -53,9 +53,15 @@ func TestTieredCache(t *testing.T) {
 
 			ExpectedOutput: "test",
 		},
+		{
+			Name:           "lru cache tiered",
+			Cache:          NewTieredCache(nil, NewLRUCache(10, 1<<20, time.Minute, ""), NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")),
+			Key:            "test_cache",
+			Value:          "test",
+			ExpectedOutput: "test",
+		},
 	}
-	GlobalCacheMonitor = NewMonitor(time.Minute, false)
-	ctx := context.Background()
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")).Context(context.Background())
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
 			if tc.Value != "" {
@@ -73,8 +79,13 @@ func TestTieredCache(t *testing.T) {
 			if tc.ExpectedErr != nil {
 				return
 			}
-			if string(value) != tc.ExpectedOutput {
-				t.Errorf("does not match expected output: %s != %s", tc.ExpectedOutput, string(value))
+			got, _, err := decodeStoredValue[string](nil, value)
+			if err != nil {
+				t.Errorf("failed decoding cache value:%s", err.Error())
+				return
+			}
+			if *got != tc.ExpectedOutput {
+				t.Errorf("does not match expected output: %s != %s", tc.ExpectedOutput, *got)
 			}
 
 			err = Set[string](ctx, tc.Group, tc.Key, tc.Value)
@@ -89,9 +100,8 @@ func TestTieredCache(t *testing.T) {
 }
 
 func TestGet(t *testing.T) {
-	GlobalCacheMonitor = NewMonitor(time.Minute, false)
 	c := NewGoCache(cache.New(5*time.Minute, time.Minute), time.Minute, "test")
-	ctx = ContextWithCache(ctx, c)
+	ctx := NewClient(c).Context(context.Background())
 	key := "test_key"
 	group := "test_group"
 
@@ -184,10 +194,10 @@ func dummyGetter(ctx context.Context) (int, error) {
 func FuzzGetSet(f *testing.F) {
 	// Seed corpus
 	f.Add("test_group", "test_key")
-	GlobalCacheMonitor = NewMonitor(time.Minute, false)
-	go GlobalCacheMonitor.Start(context.Background())
+	monitor := NewMonitor(time.Minute, false)
+	go monitor.Start(context.Background())
 	c := NewGoCache(cache.New(5*time.Minute, time.Minute), time.Minute, "test")
-	ctx := ContextWithCache(ctx, c)
+	ctx := NewClient(c, WithClientMonitor(monitor)).Context(context.Background())
 	f.Fuzz(func(t *testing.T, group, key string) {
 
 		// Attempt to GetSet with various inputs
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"github.com/patrickmn/go-cache"
+	"math/rand"
+	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -200,9 +203,9 @@ func BenchmarkConvertToBytes(b *testing.B) {
 	}
 }
 
-func benchmarkGet(b *testing.B, group string, key string, cacheSize int) {
+func benchmarkGet(b *testing.B, group string, key string, cacheSize int, opts ...ClientOption) {
 	c := NewGoCache(cache.New(5*time.Minute, time.Minute), time.Minute, "test")
-	ctx = ContextWithCache(context.Background(), c)
+	ctx := NewClient(c, opts...).Context(context.Background())
 
 	// Populate cache
 	for i := 0; i < cacheSize; i++ {
@@ -219,21 +222,21 @@ func benchmarkGet(b *testing.B, group string, key string, cacheSize int) {
 }
 
 func BenchmarkGet_SmallCacheV2(b *testing.B) {
-	GlobalCacheMonitor = NewMonitor(time.Minute, false)
-	go GlobalCacheMonitor.Start(context.Background())
-	benchmarkGet(b, "test_group", "test_key", 100)
+	monitor := NewMonitor(time.Minute, false)
+	go monitor.Start(context.Background())
+	benchmarkGet(b, "test_group", "test_key", 100, WithClientMonitor(monitor))
 }
 
 func BenchmarkGet_MediumCacheV2(b *testing.B) {
-	GlobalCacheMonitor = NewMonitor(time.Minute, false)
-	go GlobalCacheMonitor.Start(context.Background())
-	benchmarkGet(b, "test_group", "test_key", 1000)
+	monitor := NewMonitor(time.Minute, false)
+	go monitor.Start(context.Background())
+	benchmarkGet(b, "test_group", "test_key", 1000, WithClientMonitor(monitor))
 }
 
 func BenchmarkGet_LargeCacheV2(b *testing.B) {
-	GlobalCacheMonitor = NewMonitor(time.Minute, false)
-	go GlobalCacheMonitor.Start(context.Background())
-	benchmarkGet(b, "test_group", "test_key", 10000)
+	monitor := NewMonitor(time.Minute, false)
+	go monitor.Start(context.Background())
+	benchmarkGet(b, "test_group", "test_key", 10000, WithClientMonitor(monitor))
 }
 
 func BenchmarkGet_SmallCache(b *testing.B) {
@@ -248,6 +251,129 @@ func BenchmarkGet_LargeCache(b *testing.B) {
 	benchmarkGet(b, "test_group", "test_key", 10000)
 }
 
+type benchmarkUser struct {
+	ID    int
+	Name  string
+	Email string
+	Tags  []string
+}
+
+func benchmarkGetTyped(b *testing.B, c Cache) {
+	ctx := NewClient(c).Context(context.Background())
+	u := benchmarkUser{ID: 1, Name: "name", Email: "name@example.com", Tags: []string{"a", "b", "c"}}
+	_ = Set[benchmarkUser](ctx, "test_group", "user", u)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Get[benchmarkUser](ctx, "test_group", "user"); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func BenchmarkGetTyped_GoCache(b *testing.B) {
+	benchmarkGetTyped(b, NewGoCache(cache.New(5*time.Minute, time.Minute), time.Minute, "test"))
+}
+
+func BenchmarkGetTyped_ObjectCache(b *testing.B) {
+	benchmarkGetTyped(b, NewObjectCache(cache.New(5*time.Minute, time.Minute), time.Minute, "test"))
+}
+
+func BenchmarkGetTyped_TieredObjectCache(b *testing.B) {
+	benchmarkGetTyped(b, NewTieredCache(nil,
+		NewObjectCache(cache.New(5*time.Minute, time.Minute), time.Minute, "test"),
+		NewGoCache(cache.New(5*time.Minute, time.Minute), time.Minute, "test"),
+	))
+}
+
+// benchmarkHitRatio replays a skewed workload interleaved with one-off scan keys and
+// reports the share of reads served from c.
+func benchmarkHitRatio(b *testing.B, c Cache) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, 10000)
+	hits := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := "hot" + strconv.FormatUint(zipf.Uint64(), 10)
+		if i%2 == 1 {
+			key = "scan" + strconv.Itoa(i)
+		}
+		if _, err := c.GetCache(ctx, "", key); err == nil {
+			hits++
+			continue
+		}
+		_ = c.SetCache(ctx, "", key, []byte("v"))
+	}
+	b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+}
+
+func BenchmarkHitRatio_LRU(b *testing.B) {
+	benchmarkHitRatio(b, NewLRUCache(1000, 0, time.Minute, "hit_ratio"))
+}
+
+func BenchmarkHitRatio_TinyLFU(b *testing.B) {
+	benchmarkHitRatio(b, NewLRUCache(1000, 0, time.Minute, "hit_ratio", WithTinyLFU()))
+}
+
+// benchmarkParallel reads and writes 1024 keys from every GOMAXPROCS goroutine, one write
+// per 8 reads.
+func benchmarkParallel(b *testing.B, c Cache) {
+	ctx := context.Background()
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+		_ = c.SetCache(ctx, "", keys[i], []byte("value"))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%8 == 0 {
+				_ = c.SetCache(ctx, "", key, []byte("value"))
+			} else {
+				_, _ = c.GetCache(ctx, "", key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkParallel_GoCache(b *testing.B) {
+	benchmarkParallel(b, NewGoCache(cache.New(5*time.Minute, time.Minute), time.Minute, "parallel"))
+}
+
+func BenchmarkParallel_ShardedCache(b *testing.B) {
+	c := NewShardedCache(0, time.Minute, time.Minute, "parallel")
+	defer c.Close()
+	benchmarkParallel(b, c)
+}
+
+// benchmarkGC fills c with 200k small values and measures a full garbage collection.
+func benchmarkGC(b *testing.B, c Cache) {
+	ctx := context.Background()
+	for i := 0; i < 200000; i++ {
+		_ = c.SetCache(ctx, "", "key"+strconv.Itoa(i), []byte("value"))
+	}
+	runtime.GC()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+	}
+	b.StopTimer()
+	runtime.KeepAlive(c)
+}
+
+func BenchmarkGC_GoCache(b *testing.B) {
+	benchmarkGC(b, NewGoCache(cache.New(5*time.Minute, time.Minute), 5*time.Minute, "gc"))
+}
+
+func BenchmarkGC_ArenaCache(b *testing.B) {
+	benchmarkGC(b, NewArenaCache(64<<20, 0, 5*time.Minute, "gc"))
+}
+
 // BenchmarkSyncMap benchmarks sync.Map with concurrent access
 func BenchmarkSyncMap(b *testing.B) {
 	var m sync.Map
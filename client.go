@@ -0,0 +1,191 @@
+package ctx_cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	CTX_CACHE_CLIENT = "cache_ctx_client"
+)
+
+// Client owns the cache, monitor, codec, compression and key strategy used by the generic helpers,
+// so several configurations can live in one process without touching package globals.
+type Client struct {
+	cache   Cache
+	monitor CacheMonitor
+	codec   Codec
+	keys    KeyStrategy
+
+	groupCodecs map[string]Codec
+	typeCodecs  map[string]Codec
+
+	compression          Compression
+	compressionThreshold int
+
+	integrity Integrity
+	secret    []byte
+}
+
+type ClientOption func(*Client)
+
+// WithClientMonitor records group keys in monitor instead of a monitor owned by the client.
+func WithClientMonitor(monitor CacheMonitor) ClientOption {
+	return func(c *Client) {
+		c.monitor = monitor
+	}
+}
+
+// WithCodec encodes values with codec instead of the built-in encoding.
+func WithCodec(codec Codec) ClientOption {
+	return func(c *Client) {
+		c.codec = codec
+	}
+}
+
+// WithGroupCodec encodes values stored under group with codec.
+func WithGroupCodec(group string, codec Codec) ClientOption {
+	return func(c *Client) {
+		if c.groupCodecs == nil {
+			c.groupCodecs = map[string]Codec{}
+		}
+		c.groupCodecs[group] = codec
+	}
+}
+
+// WithTypeCodec encodes values of type T with codec, taking precedence over group codecs.
+func WithTypeCodec[T any](codec Codec) ClientOption {
+	return func(c *Client) {
+		if c.typeCodecs == nil {
+			c.typeCodecs = map[string]Codec{}
+		}
+		c.typeCodecs[GetTypeReflect[T]()] = codec
+	}
+}
+
+// WithKeyStrategy builds backend keys with keys instead of DefaultKeyStrategy, or
+// HashKeyStrategy when UseHash is set.
+func WithKeyStrategy(keys KeyStrategy) ClientOption {
+	return func(c *Client) {
+		c.keys = keys
+	}
+}
+
+func NewClient(cache Cache, opts ...ClientOption) *Client {
+	c := &Client{
+		cache: cache,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.monitor == nil {
+		c.monitor = NewMonitor(time.Minute, false)
+	}
+	if c.keys == nil {
+		c.keys = defaultKeyStrategy()
+	}
+	return c
+}
+
+func (c *Client) GetCache() Cache {
+	return c.cache
+}
+
+func (c *Client) GetMonitor() CacheMonitor {
+	return c.monitor
+}
+
+// Context returns ctx carrying the client, the context-based helpers called with it use
+// the client's cache, monitor, codec and key strategy.
+func (c *Client) Context(ctx context.Context) context.Context {
+	return ContextWithClient(ctx, c)
+}
+
+func ContextWithClient(ctx context.Context, client *Client) context.Context {
+	ctx = context.WithValue(ctx, CTX_CACHE_CLIENT, client) //nolint:staticcheck
+	ctx = ContextWithMonitor(ctx, client.monitor)
+	if client.cache != nil {
+		ctx = ContextWithCache(ctx, client.cache)
+	}
+	return ctx
+}
+
+var (
+	defaultClient     *Client
+	defaultClientOnce sync.Once
+)
+
+// DefaultClient returns the client used by calls whose context carries none. It is built
+// on first use from DefaultCache, or an in-memory cache when that is unset,
+// GlobalCacheMonitor and UseHash, so those must be set before the first call.
+func DefaultClient() *Client {
+	defaultClientOnce.Do(func() {
+		c := DefaultCache
+		if c == nil {
+			c = &GoCache{
+				defaultDuration: cache.DefaultExpiration,
+				cacher:          cache.New(5*time.Minute, time.Minute),
+				cacheTags:       NewCacheTags("go-cache", "backup"),
+			}
+		}
+		defaultClient = &Client{
+			cache:   c,
+			monitor: GlobalCacheMonitor,
+			keys:    defaultKeyStrategy(),
+		}
+	})
+	return defaultClient
+}
+
+// clientFromContext returns the client stored in ctx, or DefaultClient.
+func clientFromContext(ctx context.Context) *Client {
+	if ctx != nil {
+		if c, ok := ctx.Value(CTX_CACHE_CLIENT).(*Client); ok && c != nil {
+			return c
+		}
+	}
+	return DefaultClient()
+}
+
+// defaultKeyStrategy returns HashKeyStrategy when UseHash is set and DefaultKeyStrategy
+// when it is not.
+func defaultKeyStrategy() KeyStrategy {
+	if UseHash {
+		return HashKeyStrategy
+	}
+	return DefaultKeyStrategy
+}
+
+// cacheKey returns the backend key for a T stored under group and key using the key
+// strategy of the client in ctx and the key prefix registered for T.
+func cacheKey[T any](ctx context.Context, group, key string) string {
+	return clientFromContext(ctx).keys(GetTypeReflect[T](), group, policyKey[T](key))
+}
+
+// ClientGet reads group/key through client, see Get.
+func ClientGet[T any](ctx context.Context, client *Client, group, key string) (*T, error) {
+	return Get[T](client.Context(ctx), group, key)
+}
+
+// ClientSet writes group/key through client, see Set.
+func ClientSet[T any](ctx context.Context, client *Client, group, key string, data T) error {
+	return Set[T](client.Context(ctx), group, key, data)
+}
+
+// ClientSetWithExpiration writes group/key through client, see SetWithExpiration.
+func ClientSetWithExpiration[T any](ctx context.Context, client *Client, cacheTimeout time.Duration, group, key string, data T) error {
+	return SetWithExpiration[T](client.Context(ctx), cacheTimeout, group, key, data)
+}
+
+// ClientDelete removes group/key through client, see Delete.
+func ClientDelete[T any](ctx context.Context, client *Client, group, key string) error {
+	return Delete[T](client.Context(ctx), group, key)
+}
+
+// ClientFetch reads group/key through client, loading it on a miss, see Fetch.
+func ClientFetch[T any](ctx context.Context, client *Client, group, key string, loader func(ctx context.Context) (*T, error), opts ...FetchOption) (*T, error) {
+	return Fetch[T](client.Context(ctx), group, key, loader, opts...)
+}
@@ -0,0 +1,93 @@
+package ctx_cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+type countingCodec struct {
+	calls int
+}
+
+func (j *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	j.calls++
+	return json.Marshal(v)
+}
+
+func (j *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	j.calls++
+	return json.Unmarshal(data, v)
+}
+
+func (j *countingCodec) CodecID() byte {
+	return 0x80
+}
+
+func TestClientsAreIsolated(t *testing.T) {
+	ctx := context.Background()
+	c1 := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "client_1")
+	c2 := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "client_2")
+	codec := &countingCodec{}
+	a := NewClient(c1)
+	b := NewClient(c2, WithCodec(codec), WithKeyStrategy(HashKeyStrategy))
+
+	if err := ClientSetWithExpiration[string](ctx, a, time.Minute, "client_group", "key", "a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ClientSetWithExpiration[string](ctx, b, time.Minute, "client_group", "key", "b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v, err := ClientGet[string](ctx, a, "client_group", "key")
+	if err != nil || *v != "a" {
+		t.Fatalf("expected a, got %v (%v)", v, err)
+	}
+	v, err = ClientGet[string](ctx, b, "client_group", "key")
+	if err != nil || *v != "b" {
+		t.Fatalf("expected b, got %v (%v)", v, err)
+	}
+	if codec.calls == 0 {
+		t.Fatalf("expected the client codec to be used")
+	}
+	stored, ok := c2.cacher.Get(HashKeyStrategy(GetTypeReflect[string](), "client_group", "key"))
+	if !ok {
+		t.Fatalf("expected the client key strategy to be used")
+	}
+	if _, payload, _ := decodeEntryMeta(stored.([]byte)); string(payload) != `"b"` {
+		t.Fatalf("expected json payload, got %s", payload)
+	}
+
+	keys, err := b.GetMonitor().GetGroupKeys(ctx, "client_group")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("expected one key in the client monitor, got %v", keys)
+	}
+	keys, err = a.GetMonitor().GetGroupKeys(ctx, "client_group")
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("expected one key in the client monitor, got %v (%v)", keys, err)
+	}
+}
+
+func TestDefaultClient(t *testing.T) {
+	ctx := context.Background()
+	if GetCacheFromContext(ctx) != DefaultClient().GetCache() || GetMonitorFromContext(ctx) != DefaultClient().GetMonitor() {
+		t.Fatalf("expected a context without a client to use the default client")
+	}
+	if err := SetWithExpiration[string](ctx, time.Minute, "default_group", "key", "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := ClientGet[string](ctx, DefaultClient(), "default_group", "key"); err != nil || *v != "value" {
+		t.Fatalf("expected value through the default client, got %v (%v)", v, err)
+	}
+
+	other := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "not_default"))
+	if GetCacheFromContext(other.Context(ctx)) != other.GetCache() {
+		t.Fatalf("expected the client in ctx to take precedence")
+	}
+}
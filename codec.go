@@ -0,0 +1,161 @@
+package ctx_cache
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Codec encodes values stored through a Client. Marshal and Unmarshal are given a pointer
+// to the value. CodecID is recorded in every entry so values written with another codec
+// read as a cache miss instead of failing to decode, ids below 16 are reserved for the
+// built-in codecs.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	CodecID() byte
+}
+
+const (
+	// CodecBuiltin identifies values written with the built-in encoding.
+	CodecBuiltin byte = iota
+	CodecJSON
+	CodecGob
+	CodecBytes
+	CodecBinary
+)
+
+var (
+	JSONCodec Codec = jsonCodec{}
+	GobCodec  Codec = gobCodec{}
+	// BytesCodec stores []byte values as they are. Writes do not copy the value, reads
+	// return a copy the caller may modify.
+	BytesCodec Codec = bytesCodec{}
+	// BinaryCodec encodes types implementing encoding.BinaryMarshaler and
+	// encoding.BinaryUnmarshaler, it is picked automatically for them unless the type
+	// has a direct converter, like time.Time.
+	BinaryCodec Codec = binaryCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) CodecID() byte {
+	return CodecJSON
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (gobCodec) CodecID() byte {
+	return CodecGob
+}
+
+// bytesCodec passes []byte values through. Marshal does not copy since the entry envelope
+// copies the payload, Unmarshal does because in-memory backends return the slices they
+// store and a caller modifying the value would change the cached entry.
+type bytesCodec struct{}
+
+func (bytesCodec) Marshal(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case *[]byte:
+		return *b, nil
+	}
+	return nil, fmt.Errorf("bytes codec cannot marshal %T", v)
+}
+
+func (bytesCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("bytes codec cannot unmarshal into %T", v)
+	}
+	*b = bytes.Clone(data)
+	return nil
+}
+
+func (bytesCodec) CodecID() byte {
+	return CodecBytes
+}
+
+type binaryCodec struct{}
+
+func (binaryCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("binary codec cannot marshal %T", v)
+	}
+	return m.MarshalBinary()
+}
+
+func (binaryCodec) Unmarshal(data []byte, v interface{}) error {
+	u, ok := v.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("binary codec cannot unmarshal into %T", v)
+	}
+	return u.UnmarshalBinary(data)
+}
+
+func (binaryCodec) CodecID() byte {
+	return CodecBinary
+}
+
+func codecID(codec Codec) byte {
+	if codec == nil {
+		return CodecBuiltin
+	}
+	return codec.CodecID()
+}
+
+// codecFor returns the codec a T stored under group is encoded with: the client's codec
+// registered for the type, then the codec of the type's Policy, then the client's codec
+// for the group, then the client codec. Types implementing encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler without a direct converter fall back to BinaryCodec, nil
+// means the built-in encoding.
+func codecFor[T any](ctx context.Context, group string) Codec {
+	c := clientFromContext(ctx)
+	if codec, ok := c.typeCodecs[GetTypeReflect[T]()]; ok {
+		return codec
+	}
+	if codec := policyCodec[T](); codec != nil {
+		return codec
+	}
+	if codec, ok := c.groupCodecs[group]; ok {
+		return codec
+	}
+	if c.codec != nil {
+		return c.codec
+	}
+	if converterFor(reflect.TypeOf(new(T)).Elem()) != nil {
+		return nil
+	}
+	if _, ok := any(new(T)).(encoding.BinaryMarshaler); ok {
+		if _, ok := any(new(T)).(encoding.BinaryUnmarshaler); ok {
+			return BinaryCodec
+		}
+	}
+	return nil
+}
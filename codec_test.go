@@ -0,0 +1,103 @@
+package ctx_cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+type codecUser struct {
+	Name string
+	Age  int
+}
+
+// codecVersion is stored with BinaryCodec because it implements binary marshalling and has
+// no direct converter.
+type codecVersion struct {
+	Major, Minor byte
+}
+
+func (v codecVersion) MarshalBinary() ([]byte, error) {
+	return []byte{v.Major, v.Minor}, nil
+}
+
+func (v *codecVersion) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return fmt.Errorf("invalid version length %d", len(data))
+	}
+	v.Major, v.Minor = data[0], data[1]
+	return nil
+}
+
+func TestCodecs(t *testing.T) {
+	ctx := context.Background()
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "codecs")
+	client := NewClient(c, WithGroupCodec("gob_group", GobCodec), WithTypeCodec[[]byte](BytesCodec))
+
+	if err := ClientSetWithExpiration[codecUser](ctx, client, time.Minute, "gob_group", "user", codecUser{Name: "a", Age: 3}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, err := ClientGet[codecUser](ctx, client, "gob_group", "user")
+	if err != nil || u.Name != "a" || u.Age != 3 {
+		t.Fatalf("expected gob round trip, got %v (%v)", u, err)
+	}
+	data, _ := c.GetCache(ctx, "gob_group", GetKey[codecUser]("gob_group", "user"))
+	if meta, _, _ := decodeEntryMeta(data); meta.Codec != CodecGob {
+		t.Fatalf("expected gob codec id, got %d", meta.Codec)
+	}
+
+	if err := ClientSetWithExpiration[[]byte](ctx, client, time.Minute, "bytes_group", "raw", []byte("raw bytes")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ClientGet[[]byte](ctx, client, "bytes_group", "raw")
+	if err != nil || string(*b) != "raw bytes" {
+		t.Fatalf("expected bytes round trip, got %v (%v)", b, err)
+	}
+
+	version := codecVersion{Major: 1, Minor: 2}
+	if err := ClientSetWithExpiration[codecVersion](ctx, client, time.Minute, "binary_group", "version", version); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ver, err := ClientGet[codecVersion](ctx, client, "binary_group", "version")
+	if err != nil || *ver != version {
+		t.Fatalf("expected binary round trip, got %v (%v)", ver, err)
+	}
+	data, _ = c.GetCache(ctx, "binary_group", GetKey[codecVersion]("binary_group", "version"))
+	if meta, _, _ := decodeEntryMeta(data); meta.Codec != CodecBinary {
+		t.Fatalf("expected binary codec id, got %d", meta.Codec)
+	}
+
+	now := time.Now().Round(0)
+	if err := ClientSetWithExpiration[time.Time](ctx, client, time.Minute, "binary_group", "time", now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tm, err := ClientGet[time.Time](ctx, client, "binary_group", "time")
+	if err != nil || !tm.Equal(now) {
+		t.Fatalf("expected time round trip, got %v (%v)", tm, err)
+	}
+	data, _ = c.GetCache(ctx, "binary_group", GetKey[time.Time]("binary_group", "time"))
+	if meta, payload, _ := decodeEntryMeta(data); meta.Codec != CodecBuiltin || string(payload) != now.Format(time.RFC3339Nano) {
+		t.Fatalf("expected time stored by its converter, got codec %d %q", meta.Codec, payload)
+	}
+}
+
+func TestBytesCodecReturnsCopy(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(NewShardedCache(1, time.Minute, 0, "bytes_copy"), WithTypeCodec[[]byte](BytesCodec))
+
+	if err := ClientSetWithExpiration[[]byte](ctx, client, time.Minute, "bytes_group", "raw", []byte("raw bytes")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ClientGet[[]byte](ctx, client, "bytes_group", "raw")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	copy(*b, "mutated")
+	b, err = ClientGet[[]byte](ctx, client, "bytes_group", "raw")
+	if err != nil || string(*b) != "raw bytes" {
+		t.Fatalf("expected the cached value to be unchanged, got %q (%v)", *b, err)
+	}
+}
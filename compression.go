@@ -0,0 +1,105 @@
+package ctx_cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+)
+
+// Compression identifies the algorithm an entry payload is compressed with.
+type Compression byte
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionFlate
+)
+
+// DefaultCompressionThreshold is the payload size compression starts at when
+// WithCompression is given no threshold.
+const DefaultCompressionThreshold = 1024
+
+var payloadTags = NewCacheTags("payload", "ctx_cache")
+
+// WithCompression compresses payloads of at least threshold bytes with algorithm. Reads
+// decompress whatever algorithm the entry records, so it can be turned on or off freely.
+// Payload size and compression ratio metrics are only recorded for clients using it.
+func WithCompression(algorithm Compression, threshold int) ClientOption {
+	return func(c *Client) {
+		if threshold <= 0 {
+			threshold = DefaultCompressionThreshold
+		}
+		c.compression = algorithm
+		c.compressionThreshold = threshold
+	}
+}
+
+// compressPayload compresses payload with the client compression from ctx, keeping it
+// as-is when it is below the threshold or does not shrink.
+func compressPayload(ctx context.Context, payload []byte) ([]byte, Compression, error) {
+	c := clientFromContext(ctx)
+	if c.compression == CompressionNone || len(payload) < c.compressionThreshold {
+		return payload, CompressionNone, nil
+	}
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch c.compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionFlate:
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, CompressionNone, err
+		}
+		w = fw
+	default:
+		return nil, CompressionNone, fmt.Errorf("unknown compression %d", c.compression)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, CompressionNone, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, CompressionNone, err
+	}
+	if buf.Len() >= len(payload) {
+		return payload, CompressionNone, nil
+	}
+	return buf.Bytes(), c.compression, nil
+}
+
+func decompressPayload(algorithm Compression, payload []byte) ([]byte, error) {
+	var r io.ReadCloser
+	switch algorithm {
+	case CompressionNone:
+		return payload, nil
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case CompressionFlate:
+		r = flate.NewReader(bytes.NewReader(payload))
+	default:
+		return nil, fmt.Errorf("unknown compression %d", algorithm)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// recordPayload records the stored size of a payload and its stored to decoded ratio.
+// Recording costs more than the rest of a small Get, so it is only done for clients with
+// compression enabled.
+func recordPayload(ctx context.Context, cmd CacheCmd, status CacheStatus, stored, decoded int) {
+	if clientFromContext(ctx).compression == CompressionNone {
+		return
+	}
+	ratio := 1.0
+	if decoded > 0 {
+		ratio = float64(stored) / float64(decoded)
+	}
+	payloadTags.recordPayload(ctx, cmd, status, int64(stored), ratio)
+}
@@ -0,0 +1,47 @@
+package ctx_cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestCompression(t *testing.T) {
+	ctx := context.Background()
+	large := strings.Repeat("compress me ", 500)
+	for _, algorithm := range []Compression{CompressionGzip, CompressionFlate} {
+		c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "compression")
+		client := NewClient(c, WithCompression(algorithm, 256))
+
+		if err := ClientSetWithExpiration[string](ctx, client, time.Minute, "compression_group", "large", large); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, _ := c.GetCache(ctx, "compression_group", GetKey[string]("compression_group", "large"))
+		if meta, _, _ := decodeEntryMeta(data); meta.Compression != algorithm {
+			t.Fatalf("expected compression %d, got %d", algorithm, meta.Compression)
+		}
+		if len(data) >= len(large) {
+			t.Fatalf("expected stored value to shrink, got %d bytes", len(data))
+		}
+		v, err := ClientGet[string](ctx, client, "compression_group", "large")
+		if err != nil || *v != large {
+			t.Fatalf("expected large value to decompress, got %v", err)
+		}
+
+		if err := ClientSetWithExpiration[string](ctx, client, time.Minute, "compression_group", "small", "small"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, _ = c.GetCache(ctx, "compression_group", GetKey[string]("compression_group", "small"))
+		if meta, _, _ := decodeEntryMeta(data); meta.Compression != CompressionNone {
+			t.Fatalf("expected small value to stay uncompressed, got %d", meta.Compression)
+		}
+
+		v, err = Get[string](ContextWithCache(ctx, c), "compression_group", "large")
+		if err != nil || *v != large {
+			t.Fatalf("expected reads without compression configured to decompress, got %v", err)
+		}
+	}
+}
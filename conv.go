@@ -1,222 +1,236 @@
 package ctx_cache
 
 import (
-	"database/sql"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-func ConvertToBytes(data interface{}) ([]byte, error) {
-	switch v := data.(type) {
-	case string:
-		return []byte(v), nil
-	case int:
-		return strconv.AppendInt(nil, int64(v), 10), nil
-	case int8, int16, int32, int64:
-		return strconv.AppendInt(nil, toInt64(v), 10), nil
-	case uint, uint8, uint16, uint32, uint64:
-		return strconv.AppendUint(nil, toUint64(v), 10), nil
-	case float32:
-		return strconv.AppendFloat(nil, float64(v), 'f', -1, 32), nil
-	case float64:
-		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
-	case bool:
-		return strconv.AppendBool(nil, v), nil
-	case sql.NullString:
-		if v.Valid {
-			return []byte(v.String), nil
+// converter encodes a type directly instead of going through JSON.
+type converter struct {
+	encode func(v reflect.Value) ([]byte, error)
+	decode func(data []byte, v reflect.Value) error
+}
+
+// nullMarker prefixes set nullable and pointer values so they stay distinct from NULL,
+// which is stored as no bytes.
+const nullMarker = '='
+
+var (
+	converters  sync.Map
+	noConverter = &converter{}
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// converterFor returns the direct converter for t, nil when t is encoded as JSON. The
+// result is computed once per type.
+func converterFor(t reflect.Type) *converter {
+	if t == nil {
+		return nil
+	}
+	if c, ok := converters.Load(t); ok {
+		if c == noConverter {
+			return nil
 		}
-		return nil, nil
-	case sql.NullInt64:
-		if v.Valid {
-			return strconv.AppendInt(nil, v.Int64, 10), nil
+		return c.(*converter)
+	}
+	c := newConverter(t)
+	if c == nil {
+		converters.Store(t, noConverter)
+		return nil
+	}
+	converters.Store(t, c)
+	return c
+}
+
+func newConverter(t reflect.Type) *converter {
+	if t == timeType {
+		return &converter{
+			encode: func(v reflect.Value) ([]byte, error) {
+				return v.Interface().(time.Time).MarshalText()
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				return v.Addr().Interface().(*time.Time).UnmarshalText(data)
+			},
 		}
-		return nil, nil
-	case sql.NullFloat64:
-		if v.Valid {
-			return strconv.AppendFloat(nil, v.Float64, 'f', -1, 64), nil
+	}
+	if isSQLNull(t) {
+		inner := converterFor(t.Field(0).Type)
+		if inner == nil {
+			return nil
 		}
-		return nil, nil
-	case sql.NullBool:
-		if v.Valid {
-			return strconv.AppendBool(nil, v.Bool), nil
+		return nullableConverter(inner,
+			func(v reflect.Value) bool { return v.Field(1).Bool() },
+			func(v reflect.Value) reflect.Value { return v.Field(0) },
+			func(v reflect.Value) reflect.Value {
+				v.Field(1).SetBool(true)
+				return v.Field(0)
+			})
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &converter{
+			encode: func(v reflect.Value) ([]byte, error) {
+				return strconv.AppendBool(nil, v.Bool()), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				b, err := strconv.ParseBool(string(data))
+				v.SetBool(b)
+				return err
+			},
 		}
-		return nil, nil
-	default:
-		if data == nil {
-			return nil, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &converter{
+			encode: func(v reflect.Value) ([]byte, error) {
+				return strconv.AppendInt(nil, v.Int(), 10), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				i, err := strconv.ParseInt(string(data), 10, t.Bits())
+				v.SetInt(i)
+				return err
+			},
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &converter{
+			encode: func(v reflect.Value) ([]byte, error) {
+				return strconv.AppendUint(nil, v.Uint(), 10), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				u, err := strconv.ParseUint(string(data), 10, t.Bits())
+				v.SetUint(u)
+				return err
+			},
+		}
+	case reflect.Float32, reflect.Float64:
+		return &converter{
+			encode: func(v reflect.Value) ([]byte, error) {
+				return strconv.AppendFloat(nil, v.Float(), 'f', -1, t.Bits()), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				f, err := strconv.ParseFloat(string(data), t.Bits())
+				v.SetFloat(f)
+				return err
+			},
 		}
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("failed converting data to bytes(%v): %w", data, err)
+	case reflect.String:
+		return &converter{
+			encode: func(v reflect.Value) ([]byte, error) {
+				return []byte(v.String()), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				v.SetString(string(data))
+				return nil
+			},
 		}
-		return b, nil
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return nil
+		}
+		return &converter{
+			encode: func(v reflect.Value) ([]byte, error) {
+				return v.Bytes(), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				if len(data) == 0 {
+					v.SetZero()
+					return nil
+				}
+				v.SetBytes(bytes.Clone(data))
+				return nil
+			},
+		}
+	case reflect.Pointer:
+		inner := converterFor(t.Elem())
+		if inner == nil {
+			return nil
+		}
+		return nullableConverter(inner,
+			func(v reflect.Value) bool { return !v.IsNil() },
+			func(v reflect.Value) reflect.Value { return v.Elem() },
+			func(v reflect.Value) reflect.Value {
+				v.Set(reflect.New(t.Elem()))
+				return v.Elem()
+			})
 	}
+	return nil
 }
 
-func toInt64(data interface{}) int64 {
-	switch v := data.(type) {
-	case int8:
-		return int64(v)
-	case int16:
-		return int64(v)
-	case int32:
-		return int64(v)
-	case int64:
-		return v
-	default:
-		return 0
+// nullableConverter stores an unset value as no bytes and a set one as nullMarker followed
+// by the inner encoding.
+func nullableConverter(inner *converter, valid func(v reflect.Value) bool, get func(v reflect.Value) reflect.Value, set func(v reflect.Value) reflect.Value) *converter {
+	return &converter{
+		encode: func(v reflect.Value) ([]byte, error) {
+			if !valid(v) {
+				return []byte{}, nil
+			}
+			b, err := inner.encode(get(v))
+			if err != nil {
+				return nil, err
+			}
+			return append([]byte{nullMarker}, b...), nil
+		},
+		decode: func(data []byte, v reflect.Value) error {
+			if len(data) == 0 {
+				v.SetZero()
+				return nil
+			}
+			if data[0] != nullMarker {
+				return fmt.Errorf("invalid nullable value %q", data)
+			}
+			return inner.decode(data[1:], set(v))
+		},
 	}
 }
 
-func toUint64(data interface{}) uint64 {
-	switch v := data.(type) {
-	case uint8:
-		return uint64(v)
-	case uint16:
-		return uint64(v)
-	case uint32:
-		return uint64(v)
-	case uint64:
-		return v
-	default:
-		return 0
+// isSQLNull reports whether t is one of the database/sql Null types, a value field
+// followed by Valid.
+func isSQLNull(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == "database/sql" && strings.HasPrefix(t.Name(), "Null") &&
+		t.NumField() == 2 && t.Field(1).Name == "Valid" && t.Field(1).Type.Kind() == reflect.Bool
+}
+
+func ConvertToBytes(data interface{}) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+	if c := converterFor(reflect.TypeOf(data)); c != nil {
+		return c.encode(reflect.ValueOf(data))
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting data to bytes(%v): %w", data, err)
 	}
+	return b, nil
 }
 
-// ConvertBytesToType attempts to convert a []byte to a generic type T
-// ConvertBytesToType attempts to convert a []byte to a generic type T
+// ConvertBytesToType converts bytes written by ConvertToBytes back to T.
 func ConvertBytesToType[T any](data []byte) (T, error) {
 	var result T
-	var err error
-
-	switch GetTypeReflect[T]() {
-	case "int":
-		var intValue int64
-		intValue, err = strconv.ParseInt(string(data), 10, 64)
-		result = any(int(intValue)).(T)
-	case "int8":
-		var intValue int64
-		intValue, err = strconv.ParseInt(string(data), 10, 8)
-		result = any(int8(intValue)).(T)
-	case "int16":
-		var intValue int64
-		intValue, err = strconv.ParseInt(string(data), 10, 16)
-		result = any(int16(intValue)).(T)
-	case "int32":
-		var intValue int64
-		intValue, err = strconv.ParseInt(string(data), 10, 32)
-		result = any(int32(intValue)).(T)
-	case "int64":
-		var intValue int64
-		intValue, err = strconv.ParseInt(string(data), 10, 64)
-		result = any(intValue).(T)
-	case "uint":
-		var uintValue uint64
-		uintValue, err = strconv.ParseUint(string(data), 10, 64)
-		result = any(uint(uintValue)).(T)
-	case "uint8":
-		var uintValue uint64
-		uintValue, err = strconv.ParseUint(string(data), 10, 8)
-		result = any(uint8(uintValue)).(T)
-	case "uint16":
-		var uintValue uint64
-		uintValue, err = strconv.ParseUint(string(data), 10, 16)
-		result = any(uint16(uintValue)).(T)
-	case "uint32":
-		var uintValue uint64
-		uintValue, err = strconv.ParseUint(string(data), 10, 32)
-		result = any(uint32(uintValue)).(T)
-	case "uint64":
-		var uintValue uint64
-		uintValue, err = strconv.ParseUint(string(data), 10, 64)
-		result = any(uintValue).(T)
-	case "float32":
-		var floatValue float64
-		floatValue, err = strconv.ParseFloat(string(data), 32)
-		result = any(float32(floatValue)).(T)
-	case "float64":
-		var floatValue float64
-		floatValue, err = strconv.ParseFloat(string(data), 64)
-		result = any(floatValue).(T)
-	case "string":
-		result = any(string(data)).(T)
-	case "bool":
-		var boolValue bool
-		boolValue, err = strconv.ParseBool(string(data))
-		result = any(boolValue).(T)
-	case "sql.NullString":
-		var nullString sql.NullString
-		if len(data) == 0 {
-			nullString.Valid = false
-		} else {
-			nullString.String = string(data)
-			nullString.Valid = true
-		}
-		result = any(nullString).(T)
-	case "sql.NullInt64":
-		var nullInt64 sql.NullInt64
-		if len(data) == 0 {
-			nullInt64.Valid = false
-		} else {
-			var intValue int64
-			intValue, err = strconv.ParseInt(string(data), 10, 64)
-			nullInt64.Int64 = intValue
-			nullInt64.Valid = err == nil
-		}
-		result = any(nullInt64).(T)
-	case "sql.NullFloat64":
-		var nullFloat64 sql.NullFloat64
-		if len(data) == 0 {
-			nullFloat64.Valid = false
-		} else {
-			var floatValue float64
-			floatValue, err = strconv.ParseFloat(string(data), 64)
-			nullFloat64.Float64 = floatValue
-			nullFloat64.Valid = err == nil
-		}
-		result = any(nullFloat64).(T)
-	case "sql.NullBool":
-		var nullBool sql.NullBool
-		if len(data) == 0 {
-			nullBool.Valid = false
-		} else {
-			var boolValue bool
-			boolValue, err = strconv.ParseBool(string(data))
-			nullBool.Bool = boolValue
-			nullBool.Valid = err == nil
-		}
-		result = any(nullBool).(T)
-	default:
-		if data == nil {
-			return result, nil
-		}
-		// Attempt to unmarshal into the generic type
-		err = json.Unmarshal(data, &result)
-		if err != nil {
+	if c := converterFor(reflect.TypeFor[T]()); c != nil {
+		if err := c.decode(data, reflect.ValueOf(&result).Elem()); err != nil {
 			return result, fmt.Errorf("failed converting bytes to type(%v): %w", GetTypeReflect[T](), err)
 		}
+		return result, nil
+	}
+	if data == nil {
+		return result, nil
 	}
-	return result, err
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("failed converting bytes to type(%v): %w", GetTypeReflect[T](), err)
+	}
+	return result, nil
 }
 
+// CheckPrimaryType reports whether T is encoded directly rather than as JSON: booleans,
+// numbers, strings and byte slices including named types of those kinds, time.Time, the
+// database/sql Null types and pointers to any of them.
 func CheckPrimaryType[T any](val T) bool {
-	switch GetTypeReflect[T]() {
-	case "int", "int8", "int16", "int32", "int64":
-		return true
-	case "uint", "uint8", "uint16", "uint32", "uint64":
-		return true
-	case "float32", "float64":
-		return true
-	case "string":
-		return true
-	case "bool":
-		return true
-	default:
-		return false
-	}
+	return converterFor(reflect.TypeFor[T]()) != nil
 }
 
 // GetTypeReflect returns the type of a generic value using reflect
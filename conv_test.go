@@ -0,0 +1,65 @@
+package ctx_cache
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type convUserID int64
+
+type convName string
+
+func roundTrip[T any](t *testing.T, v T) {
+	t.Helper()
+	if !CheckPrimaryType(v) {
+		t.Fatalf("expected %s to be converted directly", GetTypeReflect[T]())
+	}
+	b, err := ConvertToBytes(v)
+	if err != nil {
+		t.Fatalf("failed encoding %s: %v", GetTypeReflect[T](), err)
+	}
+	got, err := ConvertBytesToType[T](b)
+	if err != nil {
+		t.Fatalf("failed decoding %s from %q: %v", GetTypeReflect[T](), b, err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Fatalf("%s did not round trip: %v != %v", GetTypeReflect[T](), got, v)
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+	i := 42
+	s := ""
+
+	roundTrip(t, 42)
+	roundTrip(t, int8(-8))
+	roundTrip(t, uint16(16))
+	roundTrip(t, float32(1.5))
+	roundTrip(t, 3.14)
+	roundTrip(t, true)
+	roundTrip(t, "hello")
+	roundTrip(t, convUserID(7))
+	roundTrip(t, convName("name"))
+	roundTrip(t, now)
+	roundTrip(t, 90*time.Second)
+	roundTrip(t, []byte("raw"))
+	roundTrip(t, &i)
+	roundTrip(t, &s)
+	roundTrip[*int](t, nil)
+	roundTrip(t, sql.NullString{String: "", Valid: true})
+	roundTrip(t, sql.NullString{})
+	roundTrip(t, sql.NullInt64{Int64: 64, Valid: true})
+	roundTrip(t, sql.NullInt32{Int32: 32, Valid: true})
+	roundTrip(t, sql.NullInt16{Int16: 16, Valid: true})
+	roundTrip(t, sql.NullFloat64{Float64: 1.25, Valid: true})
+	roundTrip(t, sql.NullBool{Bool: true, Valid: true})
+	roundTrip(t, sql.NullTime{Time: now, Valid: true})
+	roundTrip(t, sql.NullTime{})
+
+	if CheckPrimaryType(struct{ A int }{}) || CheckPrimaryType([]int{1}) {
+		t.Fatalf("expected structs and slices to be encoded as json")
+	}
+}
@@ -0,0 +1,449 @@
+package ctx_cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var _ Cache = &DiskCache{}
+var _ BatchCache = &DiskCache{}
+
+const (
+	diskMagic      = "CCD1"
+	diskTempPrefix = ".tmp-"
+	// diskHeaderSize is the size of the header written before every key and value: magic,
+	// expiry in unix nanoseconds and key length.
+	diskHeaderSize = len(diskMagic) + 8 + 2
+)
+
+// DiskCache stores every key in its own file under dir, in directories named after the
+// first bytes of the key hash. Files are written to a temporary file and renamed into
+// place so readers never see a partial value, and start with a header holding the expiry.
+// Expired files are swept in the background and the least recently used files are removed
+// once the total size exceeds the quota, counted with the EVICT command. Files written by
+// an earlier process are picked up on start, ordered by modification time.
+type DiskCache struct {
+	dir             string
+	defaultDuration time.Duration
+	maxBytes        int64
+	cacheTags       CacheTags
+
+	mu    sync.Mutex
+	size  int64
+	files map[string]*list.Element
+	order *list.List
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type diskFile struct {
+	path      string
+	size      int64
+	expiresAt int64
+}
+
+func (f *diskFile) expired(now int64) bool {
+	return f.expiresAt > 0 && now > f.expiresAt
+}
+
+func DiskCacheFlags(prefix string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(prefix+"diskcache", pflag.ExitOnError)
+	fs.String(prefix+"diskcache-dir", "", "")
+	fs.Int64(prefix+"diskcache-max-bytes", 10<<30, "0 for no limit")
+	fs.Duration(prefix+"diskcache-default-duration", 24*time.Hour, "")
+	fs.Duration(prefix+"diskcache-cleanup-duration", 10*time.Minute, "")
+
+	return fs
+}
+
+func NewDiskCacheFromFlags(prefix string) (*DiskCache, error) {
+	return NewDiskCache(viper.GetString(prefix+"diskcache-dir"), viper.GetInt64(prefix+"diskcache-max-bytes"), viper.GetDuration(prefix+"diskcache-default-duration"), viper.GetDuration(prefix+"diskcache-cleanup-duration"), prefix)
+}
+
+// NewDiskCache opens the cache in dir, creating it when needed, limited to maxBytes of
+// files, 0 for no limit. Expired files are removed every cleanupInterval until Close is
+// called, a cleanupInterval of 0 leaves them until they are read.
+func NewDiskCache(dir string, maxBytes int64, defaultDuration, cleanupInterval time.Duration, instance string) (*DiskCache, error) {
+	if dir == "" {
+		return nil, errors.New("disk cache directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed creating disk cache directory: %w", err)
+	}
+	c := &DiskCache{
+		dir:             dir,
+		defaultDuration: defaultDuration,
+		maxBytes:        maxBytes,
+		cacheTags:       NewCacheTags("disk-cache", instance),
+		files:           map[string]*list.Element{},
+		order:           list.New(),
+		stop:            make(chan struct{}),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	c.evict(context.Background())
+	if cleanupInterval > 0 {
+		go c.sweep(cleanupInterval)
+	}
+	return c, nil
+}
+
+func (c *DiskCache) GetName() string {
+	return fmt.Sprintf("DISKCACHE_%s", c.cacheTags.instance)
+}
+
+func (c *DiskCache) GetParentCaches() map[string]Cache {
+	return map[string]Cache{}
+}
+
+func (c *DiskCache) Ping(ctx context.Context) error {
+	_, err := os.Stat(c.dir)
+	return err
+}
+
+// Close stops the expiry sweeper, stored files are kept.
+func (c *DiskCache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// Bytes returns the total size of the stored files.
+func (c *DiskCache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// path returns the file key is stored in.
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name[2:4], name)
+}
+
+func (c *DiskCache) DeleteKey(ctx context.Context, key string) error {
+	path := c.path(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.forget(path)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (c *DiskCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	data, err := encodeItem(item, cacheTimeout)
+	if err != nil {
+		return err
+	}
+	if len(key) > 1<<16-1 {
+		return fmt.Errorf("disk cache key of %d bytes is too long", len(key))
+	}
+	var expiresAt int64
+	if cacheTimeout > 0 {
+		expiresAt = time.Now().Add(cacheTimeout).UnixNano()
+	}
+	header := make([]byte, 0, diskHeaderSize+len(key))
+	header = append(header, diskMagic...)
+	header = binary.LittleEndian.AppendUint64(header, uint64(expiresAt))
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(key)))
+	header = append(header, key...)
+
+	path := c.path(key)
+	tmp, err := writeTempFile(filepath.Dir(path), header, data)
+	if err != nil {
+		return fmt.Errorf("failed writing disk cache file: %w", err)
+	}
+	c.mu.Lock()
+	err = os.Rename(tmp, path)
+	if err == nil {
+		c.forget(path)
+		c.add(&diskFile{path: path, size: int64(len(header) + len(data)), expiresAt: expiresAt})
+	}
+	c.mu.Unlock()
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed writing disk cache file: %w", err)
+	}
+	c.evict(ctx)
+	return nil
+}
+
+func (c *DiskCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return c.SetCacheWithExpiration(ctx, c.defaultDuration, group, key, item)
+}
+
+func (c *DiskCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	path := c.path(key)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, storedKey, data, err := parseDiskFile(b)
+	if err != nil || storedKey != key {
+		return nil, ErrCacheMiss
+	}
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.files[path]
+	if expiresAt > 0 && now > expiresAt {
+		// A concurrent Set may have renamed a fresh file into place since it was read, so
+		// only remove it while the index still holds an expired one.
+		if ok && el.Value.(*diskFile).expired(now) {
+			c.forget(path)
+			_ = os.Remove(path)
+		}
+		return nil, ErrCacheMiss
+	}
+	if ok {
+		c.order.MoveToFront(el)
+	}
+	return data, nil
+}
+
+func (c *DiskCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	found := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		data, err := c.GetCache(ctx, group, key)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[key] = data
+	}
+	return found, nil
+}
+
+func (c *DiskCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	for key, item := range items {
+		if err := c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *DiskCache) DeleteKeys(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := c.DeleteKey(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTempFile writes header and data to a synced temporary file in dir, to be renamed
+// into place. The file is removed when writing fails.
+func writeTempFile(dir string, header, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, diskTempPrefix+"*")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(header)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// parseDiskFile splits a stored file into its expiry, key and value.
+func parseDiskFile(b []byte) (int64, string, []byte, error) {
+	if len(b) < diskHeaderSize || !bytes.Equal(b[:len(diskMagic)], []byte(diskMagic)) {
+		return 0, "", nil, errors.New("invalid disk cache file")
+	}
+	expiresAt := int64(binary.LittleEndian.Uint64(b[len(diskMagic):]))
+	keyLen := int(binary.LittleEndian.Uint16(b[len(diskMagic)+8:]))
+	if len(b) < diskHeaderSize+keyLen {
+		return 0, "", nil, errors.New("invalid disk cache file")
+	}
+	return expiresAt, string(b[diskHeaderSize : diskHeaderSize+keyLen]), b[diskHeaderSize+keyLen:], nil
+}
+
+// readDiskExpiry reads the expiry from the header of the file at path.
+func readDiskExpiry(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	header := make([]byte, diskHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(header[:len(diskMagic)], []byte(diskMagic)) {
+		return 0, errors.New("invalid disk cache file")
+	}
+	return int64(binary.LittleEndian.Uint64(header[len(diskMagic):])), nil
+}
+
+// load indexes the files left in dir by an earlier process, least recently modified
+// first. Only paths laid out by path are considered: temporary, invalid and expired
+// files among them are removed and everything else in dir is left alone.
+func (c *DiskCache) load() error {
+	type loaded struct {
+		file    *diskFile
+		modTime time.Time
+	}
+	var found []loaded
+	now := time.Now().UnixNano()
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.dir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if d.IsDir() {
+			if rel != "." && (len(parts) > 2 || !isHexName(d.Name(), 2)) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if len(parts) != 3 {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), diskTempPrefix) {
+			_ = os.Remove(path)
+			return nil
+		}
+		if name := d.Name(); !isHexName(name, sha256.Size*2) || name[:2] != parts[0] || name[2:4] != parts[1] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		expiresAt, err := readDiskExpiry(path)
+		if err != nil || (expiresAt > 0 && now > expiresAt) {
+			_ = os.Remove(path)
+			return nil
+		}
+		found = append(found, loaded{file: &diskFile{path: path, size: info.Size(), expiresAt: expiresAt}, modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed loading disk cache directory: %w", err)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].modTime.Before(found[j].modTime)
+	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, l := range found {
+		c.add(l.file)
+	}
+	return nil
+}
+
+// isHexName reports whether name is n lowercase hex digits, as written by path.
+func isHexName(name string, n int) bool {
+	if len(name) != n {
+		return false
+	}
+	for _, r := range name {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// add indexes f as the most recently used file. c.mu must be held.
+func (c *DiskCache) add(f *diskFile) {
+	c.files[f.path] = c.order.PushFront(f)
+	c.size += f.size
+}
+
+// forget drops path from the index. c.mu must be held.
+func (c *DiskCache) forget(path string) {
+	if el, ok := c.files[path]; ok {
+		f := c.order.Remove(el).(*diskFile)
+		delete(c.files, path)
+		c.size -= f.size
+	}
+}
+
+// evict removes the least recently used files until the total size is within the quota.
+func (c *DiskCache) evict(ctx context.Context) {
+	if c.maxBytes <= 0 {
+		return
+	}
+	for {
+		c.mu.Lock()
+		if c.size <= c.maxBytes || c.order.Len() == 0 {
+			c.mu.Unlock()
+			return
+		}
+		f := c.order.Back().Value.(*diskFile)
+		c.forget(f.path)
+		_ = os.Remove(f.path)
+		c.mu.Unlock()
+		c.cacheTags.record(ctx, CacheCmdEVICT, func(err error) CacheStatus {
+			return CacheStatusOK
+		})(nil)
+	}
+}
+
+// sweep removes expired files every interval until Close is called.
+func (c *DiskCache) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			now := time.Now().UnixNano()
+			c.mu.Lock()
+			for path, el := range c.files {
+				if el.Value.(*diskFile).expired(now) {
+					c.forget(path)
+					_ = os.Remove(path)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 0, time.Minute, time.Millisecond, "disk")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetCache(ctx, "", "a", []byte("value")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := c.GetCache(ctx, "", "a"); err != nil || string(v) != "value" {
+		t.Fatalf("expected value, got %q (%v)", v, err)
+	}
+	if _, err := os.Stat(c.path("a")); err != nil {
+		t.Fatalf("expected one file for the key: %v", err)
+	}
+	if rel, _ := filepath.Rel(dir, c.path("a")); filepath.Dir(filepath.Dir(rel)) == "." {
+		t.Fatalf("expected the file under hashed directories, got %s", rel)
+	}
+
+	_ = c.SetCacheWithExpiration(ctx, time.Millisecond, "", "short", []byte("v"))
+	time.Sleep(20 * time.Millisecond)
+	if _, err := os.Stat(c.path("short")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected sweeper to remove the expired file, got %v", err)
+	}
+	c.Close()
+
+	reopened, err := NewDiskCache(dir, 0, time.Minute, 0, "disk")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := reopened.GetCache(ctx, "", "a"); err != nil || string(v) != "value" {
+		t.Fatalf("expected value to survive a restart, got %q (%v)", v, err)
+	}
+	if reopened.Bytes() != c.Bytes() {
+		t.Fatalf("expected the reopened size %d to match %d", reopened.Bytes(), c.Bytes())
+	}
+}
+
+func TestDiskCacheForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	hashed := filepath.Join(dir, "ab", "cd")
+	if err := os.MkdirAll(hashed, 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	foreign := []string{filepath.Join(dir, "important.txt"), filepath.Join(dir, "ab", "notes.txt"), filepath.Join(hashed, "notes.txt")}
+	for _, path := range foreign {
+		if err := os.WriteFile(path, []byte("keep"), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	tmp := filepath.Join(hashed, diskTempPrefix+"1")
+	invalid := filepath.Join(hashed, "abcd"+strings.Repeat("0", 60))
+	for _, path := range []string{tmp, invalid} {
+		if err := os.WriteFile(path, []byte("partial"), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	c, err := NewDiskCache(dir, 0, time.Minute, 0, "disk_foreign")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	for _, path := range foreign {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %s to be left alone, got %v", path, err)
+		}
+	}
+	for _, path := range []string{tmp, invalid} {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected %s to be removed, got %v", path, err)
+		}
+	}
+	if c.Bytes() != 0 {
+		t.Fatalf("expected no files to be indexed, got %d bytes", c.Bytes())
+	}
+}
+
+func TestDiskCacheExpiredReadKeepsFreshWrite(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDiskCache(t.TempDir(), 0, time.Minute, 0, "disk_expired_read")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	for i := 0; i < 200; i++ {
+		_ = c.SetCacheWithExpiration(ctx, time.Nanosecond, "", "k", []byte("old"))
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = c.GetCache(ctx, "", "k")
+		}()
+		if err := c.SetCache(ctx, "", "k", []byte("new")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		<-done
+		if v, err := c.GetCache(ctx, "", "k"); err != nil || string(v) != "new" {
+			t.Fatalf("expected the fresh write to survive an expired read, got %q (%v)", v, err)
+		}
+	}
+}
+
+func TestDiskCacheQuota(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDiskCache(t.TempDir(), 1000, time.Minute, 0, "disk_quota")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		_ = c.SetCache(ctx, "", fmt.Sprint(i), make([]byte, 200))
+		_, _ = c.GetCache(ctx, "", "0")
+	}
+	if c.Bytes() > 1000 {
+		t.Fatalf("expected the quota to be kept, got %d bytes", c.Bytes())
+	}
+	if _, err := c.GetCache(ctx, "", "0"); err != nil {
+		t.Fatalf("expected recently read key to be kept, got %v", err)
+	}
+	if _, err := c.GetCache(ctx, "", "1"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected least recently used key to be evicted, got %v", err)
+	}
+	if _, err := os.Stat(c.path("1")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected evicted file to be removed, got %v", err)
+	}
+}
+
+func TestDiskCacheTiered(t *testing.T) {
+	disk, err := NewDiskCache(t.TempDir(), 0, time.Minute, 0, "disk_tiered")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer disk.Close()
+	memory := NewLRUCache(100, 0, time.Minute, "disk_tiered")
+	ctx := NewClient(NewTieredCache(nil, memory, disk)).Context(context.Background())
+
+	if err := SetWithExpiration[string](ctx, time.Minute, "artifacts", "report", "large"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = memory.DeleteKey(ctx, GetKey[string]("artifacts", "report"))
+	if v, err := Get[string](ctx, "artifacts", "report"); err != nil || *v != "large" {
+		t.Fatalf("expected value from the disk tier, got %v (%v)", v, err)
+	}
+	if _, err := memory.GetCache(ctx, "artifacts", GetKey[string]("artifacts", "report")); err != nil {
+		t.Fatalf("expected the memory tier to be backfilled, got %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package ctx_cache
+
+import (
+	"context"
+)
+
+const (
+	CTX_CACHE_EARLY_EXPIRATION = "cache_ctx_early_expiration"
+)
+
+// ContextWithEarlyExpiration makes GetSet calls occasionally treat a hit as a miss before
+// the hard deadline, so a single caller recomputes the value early instead of every key
+// written with the same ttl expiring at once. beta scales how early the refresh may happen,
+// 1 is the usual choice and values above 1 favour earlier refreshes.
+func ContextWithEarlyExpiration(ctx context.Context, beta float64) context.Context {
+	return context.WithValue(ctx, CTX_CACHE_EARLY_EXPIRATION, beta) //nolint:staticcheck
+}
+
+func earlyExpiration(ctx context.Context) float64 {
+	beta, _ := ctx.Value(CTX_CACHE_EARLY_EXPIRATION).(float64)
+	return beta
+}
@@ -0,0 +1,56 @@
+package ctx_cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestGetSetEarlyExpiration(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "xfetch")).Context(context.Background())
+
+	var calls atomic.Int64
+	gtr := func(ctx context.Context) (int64, error) {
+		time.Sleep(10 * time.Millisecond)
+		return calls.Add(1), nil
+	}
+
+	v, err := GetSet[int64](ctx, time.Minute, "xfetch_group", "xfetch_key", false, gtr)
+	if err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d (%v)", v, err)
+	}
+	_, meta, err := getEntry[int64](ctx, "xfetch_group", "xfetch_key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.ComputeDuration < 10*time.Millisecond {
+		t.Fatalf("expected compute duration to be recorded, got %s", meta.ComputeDuration)
+	}
+
+	v, err = GetSet[int64](ctx, time.Minute, "xfetch_group", "xfetch_key", false, gtr)
+	if err != nil || v != 1 {
+		t.Fatalf("expected cached value 1 without early expiration, got %d (%v)", v, err)
+	}
+
+	v, err = GetSet[int64](ContextWithEarlyExpiration(ctx, 1e9), time.Minute, "xfetch_group", "xfetch_key", false, gtr)
+	if err != nil || v != 2 {
+		t.Fatalf("expected early refresh value 2, got %d (%v)", v, err)
+	}
+}
+
+func TestEntryMetaIsEarlyExpired(t *testing.T) {
+	n := time.Now()
+	meta := newEntryMeta(time.Minute).WithComputeDuration(time.Second)
+	if meta.IsEarlyExpired(n, 0) {
+		t.Fatalf("expected no early expiration with beta 0")
+	}
+	if !meta.IsEarlyExpired(n.Add(time.Minute), 1) {
+		t.Fatalf("expected early expiration at the hard deadline")
+	}
+	if newEntryMeta(0).WithComputeDuration(time.Second).IsEarlyExpired(n, 1e9) {
+		t.Fatalf("expected entries without a deadline to never expire early")
+	}
+}
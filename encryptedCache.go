@@ -0,0 +1,228 @@
+package ctx_cache
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Seann-Moser/go-serve/pkg/ctxLogger"
+	"go.uber.org/zap"
+)
+
+var _ Cache = &EncryptedCache{}
+var _ BatchCache = &EncryptedCache{}
+
+const encryptedMarker byte = 0xE1
+
+// ErrDecrypt is logged when a stored value cannot be decrypted, callers see ErrCacheMiss.
+var ErrDecrypt = errors.New("cache value failed to decrypt")
+
+// KeyRing holds the AES keys used by EncryptedCache. New values are encrypted with the
+// primary key and every ciphertext records its key id, so older keys keep decrypting
+// entries until they expire.
+type KeyRing struct {
+	mu      sync.RWMutex
+	primary uint32
+	keys    map[uint32]cipher.AEAD
+}
+
+// NewKeyRing returns a key ring using key, 16, 24 or 32 bytes, as primary key id.
+func NewKeyRing(id uint32, key []byte) (*KeyRing, error) {
+	k := &KeyRing{keys: map[uint32]cipher.AEAD{}}
+	if err := k.Rotate(id, key); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// AddKey makes key available for decryption under id without encrypting new values with it.
+func (k *KeyRing) AddKey(id uint32, key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("invalid key %d: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("invalid key %d: %w", id, err)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = aead
+	return nil
+}
+
+// Rotate adds key under id and encrypts new values with it.
+func (k *KeyRing) Rotate(id uint32, key []byte) error {
+	if err := k.AddKey(id, key); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.primary = id
+	return nil
+}
+
+// RemoveKey drops id, entries encrypted with it read as a cache miss. The primary key
+// cannot be removed.
+func (k *KeyRing) RemoveKey(id uint32) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.primary {
+		return fmt.Errorf("cannot remove primary key %d", id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// seal encrypts plaintext with the primary key, binding it to the cache key.
+func (k *KeyRing) seal(key string, plaintext []byte) ([]byte, error) {
+	k.mu.RLock()
+	id := k.primary
+	aead := k.keys[id]
+	k.mu.RUnlock()
+
+	out := make([]byte, 1+4+aead.NonceSize(), 1+4+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out[0] = encryptedMarker
+	binary.BigEndian.PutUint32(out[1:5], id)
+	if _, err := rand.Read(out[5:]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, out[5:], plaintext, []byte(key)), nil
+}
+
+// open decrypts a value written by seal under the same cache key.
+func (k *KeyRing) open(key string, data []byte) ([]byte, error) {
+	if len(data) < 5 || data[0] != encryptedMarker {
+		return nil, ErrDecrypt
+	}
+	id := binary.BigEndian.Uint32(data[1:5])
+	k.mu.RLock()
+	aead, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %d", ErrDecrypt, id)
+	}
+	data = data[5:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return plaintext, nil
+}
+
+// EncryptedCache encrypts every value with AES-GCM before handing it to the wrapped cache
+// and decrypts it on read. Values that fail to decrypt are logged and read as a miss.
+// Wrap only the shared tiers, a TieredCache backfills the decrypted value into the others.
+type EncryptedCache struct {
+	cache Cache
+	keys  *KeyRing
+}
+
+func NewEncryptedCache(cache Cache, keys *KeyRing) *EncryptedCache {
+	return &EncryptedCache{
+		cache: cache,
+		keys:  keys,
+	}
+}
+
+func (e *EncryptedCache) GetName() string {
+	return fmt.Sprintf("ENCRYPTED_%s", e.cache.GetName())
+}
+
+func (e *EncryptedCache) GetParentCaches() map[string]Cache {
+	return map[string]Cache{"0": e.cache}
+}
+
+func (e *EncryptedCache) Ping(ctx context.Context) error {
+	return e.cache.Ping(ctx)
+}
+
+func (e *EncryptedCache) Close() {
+	e.cache.Close()
+}
+
+func (e *EncryptedCache) DeleteKey(ctx context.Context, key string) error {
+	return e.cache.DeleteKey(ctx, key)
+}
+
+func (e *EncryptedCache) DeleteKeys(ctx context.Context, keys []string) error {
+	return deleteKeys(ctx, e.cache, keys)
+}
+
+func (e *EncryptedCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	data, err := e.encrypt(key, item, 0)
+	if err != nil {
+		return err
+	}
+	return e.cache.SetCache(ctx, group, key, data)
+}
+
+func (e *EncryptedCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	data, err := e.encrypt(key, item, cacheTimeout)
+	if err != nil {
+		return err
+	}
+	return e.cache.SetCacheWithExpiration(ctx, cacheTimeout, group, key, data)
+}
+
+func (e *EncryptedCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	encrypted := make(map[string]interface{}, len(items))
+	for key, item := range items {
+		data, err := e.encrypt(key, item, cacheTimeout)
+		if err != nil {
+			return err
+		}
+		encrypted[key] = data
+	}
+	return setCacheMany(ctx, e.cache, cacheTimeout, group, encrypted)
+}
+
+func (e *EncryptedCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	data, err := e.cache.GetCache(ctx, group, key)
+	if err != nil {
+		return nil, err
+	}
+	return e.decrypt(ctx, key, data)
+}
+
+func (e *EncryptedCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	found, err := getCacheMany(ctx, e.cache, group, keys)
+	if err != nil {
+		return nil, err
+	}
+	for key, data := range found {
+		v, err := e.decrypt(ctx, key, data)
+		if err != nil {
+			delete(found, key)
+			continue
+		}
+		found[key] = v
+	}
+	return found, nil
+}
+
+func (e *EncryptedCache) encrypt(key string, item interface{}, cacheTimeout time.Duration) ([]byte, error) {
+	data, err := encodeItem(item, cacheTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return e.keys.seal(key, data)
+}
+
+func (e *EncryptedCache) decrypt(ctx context.Context, key string, data []byte) ([]byte, error) {
+	v, err := e.keys.open(key, data)
+	if err != nil {
+		ctxLogger.Warn(ctx, "failed decrypting cache value", zap.String("key", key), zap.Error(err))
+		return nil, ErrCacheMiss
+	}
+	return v, nil
+}
@@ -0,0 +1,58 @@
+package ctx_cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestEncryptedCache(t *testing.T) {
+	inner := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "encrypted")
+	keys, err := NewKeyRing(1, bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := NewClient(NewEncryptedCache(inner, keys)).Context(context.Background())
+
+	if err := SetWithExpiration[string](ctx, time.Minute, "pii", "old", "secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	k := GetKey[string]("pii", "old")
+	stored, _ := inner.GetCache(ctx, "pii", k)
+	if bytes.Contains(stored, []byte("secret")) {
+		t.Fatalf("expected value to be encrypted at rest")
+	}
+
+	if err := keys.Rotate(2, bytes.Repeat([]byte{2}, 32)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := Get[string](ctx, "pii", "old")
+	if err != nil || *v != "secret" {
+		t.Fatalf("expected old key to still decrypt, got %v (%v)", v, err)
+	}
+
+	if err := SetWithExpiration[string](ctx, time.Minute, "pii", "new", "secret2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err = Get[string](ctx, "pii", "new")
+	if err != nil || *v != "secret2" {
+		t.Fatalf("expected new value, got %v (%v)", v, err)
+	}
+
+	stored[len(stored)-1] ^= 0xFF
+	_ = inner.SetCache(ctx, "pii", k, stored)
+	if _, err := Get[string](ctx, "pii", "old"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected tampered value to read as a miss, got %v", err)
+	}
+
+	if err := keys.RemoveKey(1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := keys.RemoveKey(2); err == nil {
+		t.Fatalf("expected removing the primary key to fail")
+	}
+}
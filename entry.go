@@ -0,0 +1,254 @@
+package ctx_cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"time"
+)
+
+const (
+	entryVersion    byte = 5
+	entryHeaderSize      = 4 + 8*6
+)
+
+const (
+	entryFlagTombstone byte = 1 << 0
+	entryFlagCRC32     byte = 1 << 1
+	entryFlagHMAC      byte = 1 << 2
+)
+
+var entryMagic = []byte{0xC7, 0xCA}
+
+// entryMeta is stored in front of every value a backend writes so the codec, type, write
+// time and deadlines survive in any backend that can hold bytes, and values decode the
+// same way whichever backend served them.
+type entryMeta struct {
+	CreatedAt     time.Time
+	SoftExpiresAt time.Time
+	ExpiresAt     time.Time
+	StaleUntil    time.Time
+	// ComputeDuration is how long the loader took to produce the value.
+	ComputeDuration time.Duration
+	// Tombstone marks a cached negative loader result, the payload holds the error message.
+	Tombstone bool
+	// Codec identifies the codec the payload was written with.
+	Codec byte
+	// Compression is the algorithm the payload is compressed with.
+	Compression Compression
+	// TypeHash fingerprints the type the payload was written for, zero when unknown.
+	TypeHash uint64
+
+	// Integrity is the digest appended after the payload.
+	Integrity Integrity
+
+	// decodedSize is the payload size after decompression, set when the entry is read.
+	decodedSize int
+	// secret keys the HMAC written by Encode.
+	secret []byte
+	// signed and digest hold the bytes covered by the digest and the digest read back.
+	signed []byte
+	digest []byte
+}
+
+func newEntryMeta(cacheTimeout time.Duration) entryMeta {
+	n := time.Now()
+	e := entryMeta{CreatedAt: n}
+	if cacheTimeout > 0 {
+		e.ExpiresAt = n.Add(cacheTimeout)
+	}
+	return e
+}
+
+// WithSoftTimeout sets the deadline after which the value is served while being revalidated.
+func (e entryMeta) WithSoftTimeout(softTimeout time.Duration) entryMeta {
+	if softTimeout > 0 && (e.ExpiresAt.IsZero() || e.CreatedAt.Add(softTimeout).Before(e.ExpiresAt)) {
+		e.SoftExpiresAt = e.CreatedAt.Add(softTimeout)
+	}
+	return e
+}
+
+// WithStaleTimeout keeps the entry stored for grace past its hard deadline so it can be
+// served when the loader fails.
+func (e entryMeta) WithStaleTimeout(grace time.Duration) entryMeta {
+	if grace > 0 && !e.ExpiresAt.IsZero() {
+		e.StaleUntil = e.ExpiresAt.Add(grace)
+	}
+	return e
+}
+
+// WithComputeDuration records how long the loader took, used for early expiration.
+func (e entryMeta) WithComputeDuration(d time.Duration) entryMeta {
+	e.ComputeDuration = d
+	return e
+}
+
+// StoreTimeout returns the ttl the backend should keep the entry for.
+func (e entryMeta) StoreTimeout(cacheTimeout time.Duration) time.Duration {
+	if e.StaleUntil.IsZero() {
+		return cacheTimeout
+	}
+	return e.StaleUntil.Sub(e.CreatedAt)
+}
+
+// IsStale reports whether the soft deadline has passed and the value should be revalidated.
+func (e entryMeta) IsStale(now time.Time) bool {
+	return !e.SoftExpiresAt.IsZero() && now.After(e.SoftExpiresAt)
+}
+
+// IsExpired reports whether the hard deadline has passed.
+func (e entryMeta) IsExpired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
+// IsEarlyExpired reports whether the entry should be recomputed ahead of its hard deadline.
+// The chance grows as the deadline approaches and with the recorded compute duration
+// scaled by beta, so callers sharing a ttl spread their refreshes out (XFetch).
+func (e entryMeta) IsEarlyExpired(now time.Time, beta float64) bool {
+	if beta <= 0 || e.ComputeDuration <= 0 || e.ExpiresAt.IsZero() {
+		return false
+	}
+	gap := -float64(e.ComputeDuration) * beta * math.Log(1-rand.Float64())
+	return gap >= float64(e.ExpiresAt.Sub(now))
+}
+
+// SoftTimeout returns the soft window the entry was written with.
+func (e entryMeta) SoftTimeout() time.Duration {
+	if e.SoftExpiresAt.IsZero() {
+		return 0
+	}
+	return e.SoftExpiresAt.Sub(e.CreatedAt)
+}
+
+// IsServable reports whether an expired entry is still inside its stale-if-error window.
+func (e entryMeta) IsServable(now time.Time) bool {
+	return !e.IsExpired(now) || (!e.StaleUntil.IsZero() && !now.After(e.StaleUntil))
+}
+
+// TTL returns the time the backend should still keep the entry, false when it has no deadline.
+func (e entryMeta) TTL(now time.Time) (time.Duration, bool) {
+	if !e.StaleUntil.IsZero() {
+		return e.StaleUntil.Sub(now), true
+	}
+	if e.ExpiresAt.IsZero() {
+		return 0, false
+	}
+	return e.ExpiresAt.Sub(now), true
+}
+
+func (e entryMeta) Encode(payload []byte) []byte {
+	out := make([]byte, 0, len(entryMagic)+entryHeaderSize+len(payload))
+	out = append(out, entryMagic...)
+	var flags byte
+	if e.Tombstone {
+		flags |= entryFlagTombstone
+	}
+	switch e.Integrity {
+	case IntegrityCRC32:
+		flags |= entryFlagCRC32
+	case IntegrityHMAC:
+		flags |= entryFlagHMAC
+	}
+	out = append(out, entryVersion, flags, e.Codec, byte(e.Compression))
+	out = binary.BigEndian.AppendUint64(out, uint64(unixNano(e.CreatedAt)))
+	out = binary.BigEndian.AppendUint64(out, uint64(unixNano(e.SoftExpiresAt)))
+	out = binary.BigEndian.AppendUint64(out, uint64(unixNano(e.ExpiresAt)))
+	out = binary.BigEndian.AppendUint64(out, uint64(unixNano(e.StaleUntil)))
+	out = binary.BigEndian.AppendUint64(out, uint64(e.ComputeDuration))
+	out = binary.BigEndian.AppendUint64(out, e.TypeHash)
+	out = append(out, payload...)
+	return append(out, e.Integrity.digest(e.secret, out)...)
+}
+
+// decodeEntryMeta splits stored bytes into the header and payload. Values written
+// before the header existed are returned unchanged with a zero header, values written
+// with another header version read as a cache miss.
+func decodeEntryMeta(data []byte) (entryMeta, []byte, error) {
+	var e entryMeta
+	if len(data) < len(entryMagic)+entryHeaderSize || !bytes.HasPrefix(data, entryMagic) {
+		return e, data, nil
+	}
+	h := data[len(entryMagic):]
+	if h[0] != entryVersion {
+		return e, nil, ErrCacheMiss
+	}
+	e.Tombstone = h[1]&entryFlagTombstone != 0
+	switch {
+	case h[1]&entryFlagCRC32 != 0:
+		e.Integrity = IntegrityCRC32
+	case h[1]&entryFlagHMAC != 0:
+		e.Integrity = IntegrityHMAC
+	}
+	e.Codec = h[2]
+	e.Compression = Compression(h[3])
+	h = h[4:]
+	e.CreatedAt = fromUnixNano(int64(binary.BigEndian.Uint64(h[0:8])))
+	e.SoftExpiresAt = fromUnixNano(int64(binary.BigEndian.Uint64(h[8:16])))
+	e.ExpiresAt = fromUnixNano(int64(binary.BigEndian.Uint64(h[16:24])))
+	e.StaleUntil = fromUnixNano(int64(binary.BigEndian.Uint64(h[24:32])))
+	e.ComputeDuration = time.Duration(binary.BigEndian.Uint64(h[32:40]))
+	e.TypeHash = binary.BigEndian.Uint64(h[40:48])
+	payload := data[len(entryMagic)+entryHeaderSize:]
+	if n := e.Integrity.size(); n > 0 {
+		if len(payload) < n {
+			return e, nil, nil
+		}
+		e.signed = data[:len(data)-n]
+		e.digest = data[len(data)-n:]
+		payload = payload[:len(payload)-n]
+	}
+	return e, payload, nil
+}
+
+// typeHash fingerprints T, see typeHashOf.
+func typeHash[T any]() uint64 {
+	return typeHashOf(reflect.TypeFor[T]())
+}
+
+// encodeItem wraps an item handed straight to a backend in the entry envelope with the
+// built-in encoding. Bytes are stored as-is since the generic helpers already encode them.
+func encodeItem(item interface{}, cacheTimeout time.Duration) ([]byte, error) {
+	if b, ok := item.([]byte); ok {
+		return b, nil
+	}
+	payload, err := encodeBuiltin(item)
+	if err != nil {
+		return nil, err
+	}
+	return newEntryMeta(cacheTimeout).Encode(payload), nil
+}
+
+// encodeBuiltin encodes item the way encodeValue does for its dynamic type without a codec.
+func encodeBuiltin(item interface{}) ([]byte, error) {
+	if converterFor(reflect.TypeOf(item)) != nil {
+		return ConvertToBytes(item)
+	}
+	return json.Marshal(Wrapper[interface{}]{Data: item})
+}
+
+// entryTTL returns the remaining hard TTL of stored bytes, used to copy values between
+// tiers without extending their lifetime.
+func entryTTL(data []byte) (time.Duration, bool) {
+	e, _, err := decodeEntryMeta(data)
+	if err != nil {
+		return 0, false
+	}
+	return e.TTL(time.Now())
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,41 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestEntryEnvelope(t *testing.T) {
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "envelope")
+	ctx := NewClient(c).Context(context.Background())
+
+	if err := c.SetCache(ctx, "envelope_group", GetKey[string]("envelope_group", "raw"), "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := Get[string](ctx, "envelope_group", "raw")
+	if err != nil || *v != "value" {
+		t.Fatalf("expected raw backend write to decode as value, got %v (%v)", v, err)
+	}
+
+	if err := SetWithExpiration[string](ctx, time.Minute, "envelope_group", "typed", "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := c.GetCache(ctx, "envelope_group", GetKey[string]("envelope_group", "typed"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	meta, _, err := decodeEntryMeta(data)
+	if err != nil || meta.TypeHash != typeHash[string]() || meta.Codec != CodecBuiltin {
+		t.Fatalf("expected typed builtin entry, got %+v (%v)", meta, err)
+	}
+	if _, _, err := decodeStoredValue[int](nil, data); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected entry read as another type to miss, got %v", err)
+	}
+	if _, _, err := decodeStoredValue[string](&countingCodec{}, data); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected entry read with another codec to miss, got %v", err)
+	}
+}
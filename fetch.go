@@ -0,0 +1,158 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/trace"
+	"time"
+)
+
+type fetchOptions struct {
+	cacheTimeout time.Duration
+	refresh      bool
+	isValid      interface{}
+	allowNil     bool
+	rawErrors    bool
+	cache        Cache
+	monitor      CacheMonitor
+}
+
+// FetchOption configures a Fetch call.
+type FetchOption func(*fetchOptions)
+
+// WithTTL sets the hard deadline loaded values are cached for.
+func WithTTL(cacheTimeout time.Duration) FetchOption {
+	return func(o *fetchOptions) {
+		o.cacheTimeout = cacheTimeout
+	}
+}
+
+// WithRefresh skips the cache read and always runs the loader when refresh is true.
+func WithRefresh(refresh bool) FetchOption {
+	return func(o *fetchOptions) {
+		o.refresh = refresh
+	}
+}
+
+// WithCheck reloads cached values isValid rejects. T must match the type passed to Fetch,
+// which otherwise returns an error without reading the cache.
+func WithCheck[T any](isValid func(ctx context.Context, data *T) bool) FetchOption {
+	return func(o *fetchOptions) {
+		o.isValid = isValid
+	}
+}
+
+// WithAllowNil returns a nil loader result as nil without an error instead of ErrCacheGet.
+// Nil results are never cached.
+func WithAllowNil() FetchOption {
+	return func(o *fetchOptions) {
+		o.allowNil = true
+	}
+}
+
+// WithCache reads and writes cache instead of the cache stored in ctx.
+func WithCache(cache Cache) FetchOption {
+	return func(o *fetchOptions) {
+		o.cache = cache
+	}
+}
+
+// WithMonitor records written group keys in monitor instead of the monitor of the client
+// in ctx.
+func WithMonitor(monitor CacheMonitor) FetchOption {
+	return func(o *fetchOptions) {
+		o.monitor = monitor
+	}
+}
+
+// withRawErrors returns loader errors unwrapped, as GetSet and GetSetCheck always did.
+func withRawErrors() FetchOption {
+	return func(o *fetchOptions) {
+		o.rawErrors = true
+	}
+}
+
+// Fetch returns the value cached for group/key, calling loader and caching its result
+// on a miss. Concurrent misses share one loader call and the stale-while-revalidate,
+// stale-if-error, early expiration and negative caching modes set on ctx all apply.
+// Loader errors are wrapped with the group and key.
+func Fetch[T any](ctx context.Context, group, key string, loader func(ctx context.Context) (*T, error), opts ...FetchOption) (*T, error) {
+	o := fetchOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.cache != nil {
+		ctx = ContextWithCache(ctx, o.cache)
+	}
+	if o.monitor != nil {
+		ctx = ContextWithMonitor(ctx, o.monitor)
+	}
+	isValid, ok := o.isValid.(func(ctx context.Context, data *T) bool)
+	if !ok && o.isValid != nil {
+		return nil, fmt.Errorf("fetch of %s given a check of type %T", GetTypeReflect[T](), o.isValid)
+	}
+	group = policyGroup[T](group, key)
+	o.cacheTimeout = policyTTL[T](o.cacheTimeout)
+
+	load := func(ctx context.Context) (*T, error) {
+		setFunctionCall := trace.StartRegion(ctx, "set_function_call")
+		start := time.Now()
+		nv, err := loader(ctx)
+		setFunctionCall.End()
+		if err != nil {
+			if !o.rawErrors {
+				err = fmt.Errorf("failed getting cache value(group:%s, key:%s): %w", group, key, err)
+			}
+			return nil, cacheLoadError[T](ctx, group, key, err)
+		}
+		if nv == nil {
+			if o.allowNil {
+				return nil, nil
+			}
+			return nil, ErrCacheGet
+		}
+		setWithExpiration := trace.StartRegion(ctx, "set_with_expiration")
+		defer setWithExpiration.End()
+		return nv, setLoaded[T](ctx, o.cacheTimeout, time.Since(start), group, key, *nv)
+	}
+	if o.refresh {
+		refresh := trace.StartRegion(ctx, "refresh_function")
+		defer refresh.End()
+		return load(ctx)
+	}
+	get := trace.StartRegion(ctx, "get")
+	v, stale, err := getRevalidate[T](ctx, group, key, load)
+	get.End()
+	if isNegativeError(err) {
+		return nil, err
+	}
+	if errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrCacheUpdated) || v == nil || (isValid != nil && !isValid(ctx, v)) {
+		nv, err := loadOnce[*T](ctx, cacheKey[T](ctx, group, key), load)
+		if sv, staleErr := serveStale[T](ctx, group, key, stale, err); sv != nil {
+			return sv, staleErr
+		}
+		return nv, err
+	}
+	return v, nil
+}
+
+// valueLoader adapts a loader returning T to the pointer loader Fetch takes.
+func valueLoader[T any](gtr func(ctx context.Context) (T, error)) func(ctx context.Context) (*T, error) {
+	return func(ctx context.Context) (*T, error) {
+		v, err := gtr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &v, nil
+	}
+}
+
+// fetchValue dereferences a Fetch result for the value returning wrappers.
+func fetchValue[T any](v *T, err error) (T, error) {
+	if v == nil {
+		var tmp T
+		return tmp, err
+	}
+	return *v, err
+}
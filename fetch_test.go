@@ -0,0 +1,94 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestFetchOptions(t *testing.T) {
+	ctxCache := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "fetch_ctx")
+	override := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "fetch_override")
+	ctx := NewClient(ctxCache).Context(context.Background())
+
+	calls := 0
+	loader := func(ctx context.Context) (*string, error) {
+		calls++
+		v := "value"
+		return &v, nil
+	}
+
+	v, err := Fetch[string](ctx, "fetch_group", "fetch_key", loader, WithTTL(time.Minute), WithCache(override))
+	if err != nil || *v != "value" {
+		t.Fatalf("expected value, got %v (%v)", v, err)
+	}
+	k := GetKey[string]("fetch_group", "fetch_key")
+	if _, ok := override.cacher.Get(k); !ok {
+		t.Fatalf("expected value to be written to the override cache")
+	}
+	if _, ok := ctxCache.cacher.Get(k); ok {
+		t.Fatalf("expected the context cache to be left alone")
+	}
+
+	_, err = Fetch[string](ctx, "fetch_group", "fetch_key", loader, WithTTL(time.Minute), WithCache(override))
+	if err != nil || calls != 1 {
+		t.Fatalf("expected cached value, got %d loader calls (%v)", calls, err)
+	}
+
+	_, err = Fetch[string](ctx, "fetch_group", "fetch_key", loader, WithTTL(time.Minute), WithCache(override), WithCheck(func(ctx context.Context, data *string) bool {
+		return false
+	}))
+	if err != nil || calls != 2 {
+		t.Fatalf("expected invalid value to be reloaded, got %d loader calls (%v)", calls, err)
+	}
+
+	nilLoader := func(ctx context.Context) (*string, error) {
+		return nil, nil
+	}
+	if _, err := Fetch[string](ctx, "fetch_group", "nil_key", nilLoader); !errors.Is(err, ErrCacheGet) {
+		t.Fatalf("expected ErrCacheGet, got %v", err)
+	}
+	v, err = Fetch[string](ctx, "fetch_group", "nil_key", nilLoader, WithAllowNil())
+	if err != nil || v != nil {
+		t.Fatalf("expected nil without error, got %v (%v)", v, err)
+	}
+}
+
+func TestFetchWithMonitor(t *testing.T) {
+	monitor := NewMonitor(time.Minute, false)
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "fetch_monitor")).Context(context.Background())
+
+	_, err := Fetch[string](ctx, "monitor_group", "monitor_key", func(ctx context.Context) (*string, error) {
+		v := "value"
+		return &v, nil
+	}, WithTTL(time.Minute), WithMonitor(monitor))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys, err := monitor.GetGroupKeys(ctx, "monitor_group")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := keys[GetKey[string]("monitor_group", "monitor_key")]; !ok {
+		t.Fatalf("expected key to be recorded in the override monitor, got %v", keys)
+	}
+}
+
+func TestFetchCheckTypeMismatch(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "fetch_check")).Context(context.Background())
+
+	called := false
+	_, err := Fetch[string](ctx, "check_group", "check_key", func(ctx context.Context) (*string, error) {
+		called = true
+		v := "value"
+		return &v, nil
+	}, WithCheck[int](func(ctx context.Context, data *int) bool {
+		return true
+	}))
+	if err == nil || called {
+		t.Fatalf("expected a check for another type to fail the fetch, got %v (loader called: %t)", err, called)
+	}
+}
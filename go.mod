@@ -4,6 +4,7 @@ go 1.22.5
 
 require (
 	github.com/Seann-Moser/go-serve v0.9.12
+	github.com/cespare/xxhash/v2 v2.3.0
 	github.com/orijtech/gomemcache v0.0.1
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/redis/go-redis/v9 v9.7.3
@@ -16,7 +17,6 @@ require (
 )
 
 require (
-	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
@@ -12,6 +12,7 @@ import (
 )
 
 var _ Cache = &GoCache{}
+var _ BatchCache = &GoCache{}
 
 type GoCache struct {
 	defaultDuration time.Duration
@@ -74,7 +75,11 @@ func (c *GoCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.
 	//	s(err)
 	//}()
 
-	c.cacher.Set(key, item, cacheTimeout)
+	data, err := encodeItem(item, cacheTimeout)
+	if err != nil {
+		return err
+	}
+	c.cacher.Set(key, data, cacheTimeout)
 	return nil
 }
 
@@ -104,3 +109,37 @@ func (c *GoCache) GetCache(ctx context.Context, group, key string) ([]byte, erro
 		return ConvertToBytes(data)
 	}
 }
+
+func (c *GoCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	found := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		data, ok := c.cacher.Get(key)
+		if !ok {
+			continue
+		}
+		v, err := ConvertToBytes(data)
+		if err != nil {
+			return nil, err
+		}
+		found[key] = v
+	}
+	return found, nil
+}
+
+func (c *GoCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	for key, item := range items {
+		data, err := encodeItem(item, cacheTimeout)
+		if err != nil {
+			return err
+		}
+		c.cacher.Set(key, data, cacheTimeout)
+	}
+	return nil
+}
+
+func (c *GoCache) DeleteKeys(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		c.cacher.Delete(key)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package ctx_cache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/Seann-Moser/go-serve/pkg/ctxLogger"
+	"go.uber.org/zap"
+)
+
+// Integrity identifies the digest stored after an entry payload.
+type Integrity byte
+
+const (
+	IntegrityNone Integrity = iota
+	// IntegrityCRC32 detects accidental corruption such as truncated or mixed up values.
+	IntegrityCRC32
+	// IntegrityHMAC detects corruption and values not written by a holder of the secret.
+	IntegrityHMAC
+)
+
+var (
+	crcTable      = crc32.MakeTable(crc32.Castagnoli)
+	integrityTags = NewCacheTags("integrity", "ctx_cache")
+)
+
+// WithChecksum stores a CRC32 checksum with every value and verifies it on read.
+func WithChecksum() ClientOption {
+	return func(c *Client) {
+		c.integrity = IntegrityCRC32
+		c.secret = nil
+	}
+}
+
+// WithHMAC stores an HMAC-SHA256 of every value keyed by secret and verifies it on read.
+func WithHMAC(secret []byte) ClientOption {
+	return func(c *Client) {
+		c.integrity = IntegrityHMAC
+		c.secret = secret
+	}
+}
+
+func (i Integrity) size() int {
+	switch i {
+	case IntegrityCRC32:
+		return crc32.Size
+	case IntegrityHMAC:
+		return sha256.Size
+	}
+	return 0
+}
+
+func (i Integrity) digest(secret, data []byte) []byte {
+	switch i {
+	case IntegrityCRC32:
+		return binary.BigEndian.AppendUint32(nil, crc32.Checksum(data, crcTable))
+	case IntegrityHMAC:
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(data)
+		return mac.Sum(nil)
+	}
+	return nil
+}
+
+// withIntegrity sets the digest the client in ctx writes with every entry.
+func (e entryMeta) withIntegrity(ctx context.Context) entryMeta {
+	c := clientFromContext(ctx)
+	e.Integrity = c.integrity
+	e.secret = c.secret
+	return e
+}
+
+// verify reports whether the stored digest matches the entry. Entries without a digest
+// are only accepted when the client does not require one, and clients using HMAC only
+// accept HMAC entries so a forged value cannot pass with a plain checksum.
+func (e entryMeta) verify(c *Client) bool {
+	if e.Integrity == IntegrityNone {
+		return c == nil || c.integrity == IntegrityNone
+	}
+	if c != nil && c.integrity == IntegrityHMAC && e.Integrity != IntegrityHMAC {
+		return false
+	}
+	if e.digest == nil {
+		return false
+	}
+	var secret []byte
+	if e.Integrity == IntegrityHMAC {
+		if c == nil || c.integrity != IntegrityHMAC {
+			return false
+		}
+		secret = c.secret
+	}
+	return hmac.Equal(e.digest, e.Integrity.digest(secret, e.signed))
+}
+
+// checkIntegrity verifies data read from key. Corrupt entries are deleted, logged and
+// recorded, and read as a cache miss.
+func checkIntegrity(ctx context.Context, key string, data []byte) error {
+	meta, _, err := decodeEntryMeta(data)
+	if err != nil {
+		return err
+	}
+	if meta.verify(clientFromContext(ctx)) {
+		return nil
+	}
+	ctxLogger.Warn(ctx, "deleting corrupt cache value", zap.String("key", key))
+	integrityTags.record(ctx, CacheCmdGET, func(err error) CacheStatus {
+		return CacheStatusCORRUPT
+	})(ErrCacheMiss)
+	_ = GetCacheFromContext(ctx).DeleteKey(ctx, key)
+	return ErrCacheMiss
+}
@@ -0,0 +1,64 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestIntegrity(t *testing.T) {
+	ctx := context.Background()
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "integrity")
+	k := GetKey[string]("integrity_group", "key")
+
+	for _, opt := range []ClientOption{WithChecksum(), WithHMAC([]byte("secret"))} {
+		client := NewClient(c, opt)
+		if err := ClientSetWithExpiration[string](ctx, client, time.Minute, "integrity_group", "key", "value"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		v, err := ClientGet[string](ctx, client, "integrity_group", "key")
+		if err != nil || *v != "value" {
+			t.Fatalf("expected value, got %v (%v)", v, err)
+		}
+
+		data, _ := c.GetCache(ctx, "integrity_group", k)
+		corrupt := append([]byte{}, data...)
+		corrupt[len(corrupt)-40] ^= 0xFF
+		_ = c.SetCache(ctx, "integrity_group", k, corrupt)
+		if _, err := ClientGet[string](ctx, client, "integrity_group", "key"); !errors.Is(err, ErrCacheMiss) {
+			t.Fatalf("expected corrupt value to read as a miss, got %v", err)
+		}
+		if _, err := c.GetCache(ctx, "integrity_group", k); !errors.Is(err, ErrCacheMiss) {
+			t.Fatalf("expected corrupt value to be deleted, got %v", err)
+		}
+	}
+
+	client := NewClient(c, WithHMAC([]byte("secret")))
+	if err := ClientSetWithExpiration[string](ctx, client, time.Minute, "integrity_group", "key", "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other := NewClient(c, WithHMAC([]byte("other")))
+	if _, err := ClientGet[string](ctx, other, "integrity_group", "key"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected value signed with another secret to read as a miss, got %v", err)
+	}
+
+	_ = c.SetCache(ctx, "integrity_group", k, []byte("garbage"))
+	if _, err := ClientGet[string](ctx, NewClient(c, WithChecksum()), "integrity_group", "key"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected value without a checksum to read as a miss, got %v", err)
+	}
+}
+
+func TestIntegrityHMACDowngrade(t *testing.T) {
+	ctx := context.Background()
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "integrity_downgrade")
+
+	if err := ClientSetWithExpiration[string](ctx, NewClient(c, WithChecksum()), time.Minute, "integrity_group", "key", "forged"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ClientGet[string](ctx, NewClient(c, WithHMAC([]byte("secret"))), "integrity_group", "key"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected value with only a checksum to read as a miss, got %v", err)
+	}
+}
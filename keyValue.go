@@ -0,0 +1,207 @@
+package ctx_cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheKey is implemented by key values that build their own key string.
+type CacheKey interface {
+	CacheKey() string
+}
+
+// keyEncoder appends the encoding of a key value to b.
+type keyEncoder func(b []byte, v reflect.Value) ([]byte, error)
+
+var (
+	keyEncoders     sync.Map
+	keyValueEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `=`, `\=`, `{`, `\{`, `}`, `\}`, `[`, `\[`, `]`, `\]`)
+)
+
+// KeyString returns the key string for key: its CacheKey method when it has one, the value
+// itself for strings, numbers and the other types ConvertToBytes encodes directly, and
+// otherwise its exported fields and elements in declaration order, as in
+// "TenantID=1,ID=2". Parts are escaped so distinct values never share a key.
+func KeyString[K comparable](key K) (string, error) {
+	if k, ok := any(key).(CacheKey); ok {
+		return k.CacheKey(), nil
+	}
+	v := reflect.ValueOf(&key).Elem()
+	if c := converterFor(v.Type()); c != nil {
+		b, err := c.encode(v)
+		return string(b), err
+	}
+	enc, err := keyEncoderFor(v.Type())
+	if err != nil {
+		return "", err
+	}
+	b, err := enc(nil, v)
+	return string(b), err
+}
+
+// keyEncoderFor returns the encoder for t, computed once per type.
+func keyEncoderFor(t reflect.Type) (keyEncoder, error) {
+	if enc, ok := keyEncoders.Load(t); ok {
+		return enc.(keyEncoder), nil
+	}
+	enc, err := newKeyEncoder(t)
+	if err != nil {
+		return nil, err
+	}
+	keyEncoders.Store(t, enc)
+	return enc, nil
+}
+
+func newKeyEncoder(t reflect.Type) (keyEncoder, error) {
+	if c := converterFor(t); c != nil {
+		return func(b []byte, v reflect.Value) ([]byte, error) {
+			data, err := c.encode(v)
+			if err != nil {
+				return nil, err
+			}
+			return append(b, keyValueEscaper.Replace(string(data))...), nil
+		}, nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		type field struct {
+			index int
+			name  string
+			enc   keyEncoder
+		}
+		fields := make([]field, 0, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				return nil, fmt.Errorf("key type %s has unexported field %s", t, f.Name)
+			}
+			enc, err := nestedKeyEncoder(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field{index: i, name: f.Name, enc: enc})
+		}
+		return func(b []byte, v reflect.Value) ([]byte, error) {
+			var err error
+			for i, f := range fields {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				b = append(append(b, f.name...), '=')
+				if b, err = f.enc(b, v.Field(f.index)); err != nil {
+					return nil, err
+				}
+			}
+			return b, nil
+		}, nil
+	case reflect.Array:
+		enc, err := nestedKeyEncoder(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, v reflect.Value) ([]byte, error) {
+			var err error
+			for i := 0; i < v.Len(); i++ {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				if b, err = enc(b, v.Index(i)); err != nil {
+					return nil, err
+				}
+			}
+			return b, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", t)
+}
+
+// nestedKeyEncoder encodes structs and arrays inside another key in braces.
+func nestedKeyEncoder(t reflect.Type) (keyEncoder, error) {
+	enc, err := keyEncoderFor(t)
+	if err != nil || converterFor(t) != nil {
+		return enc, err
+	}
+	start, end := byte('{'), byte('}')
+	if t.Kind() == reflect.Array {
+		start, end = '[', ']'
+	}
+	return func(b []byte, v reflect.Value) ([]byte, error) {
+		b, err := enc(append(b, start), v)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, end), nil
+	}, nil
+}
+
+// keyGroup returns the group for key, the name of key when it implements CacheObject and
+// otherwise the group registered for T.
+func keyGroup[T any, K comparable](key K, keyString string) string {
+	if o, ok := any(key).(CacheObject); ok {
+		return o.GetName()
+	}
+	return policyGroup[T]("", keyString)
+}
+
+// keyParts returns the group and key string T is stored under for key.
+func keyParts[T any, K comparable](key K) (string, string, error) {
+	k, err := KeyString(key)
+	if err != nil {
+		return "", "", err
+	}
+	return keyGroup[T](key, k), k, nil
+}
+
+// GetByKey reads the T stored for the key value key, see KeyString.
+func GetByKey[T any, K comparable](ctx context.Context, key K) (*T, error) {
+	group, k, err := keyParts[T](key)
+	if err != nil {
+		return nil, err
+	}
+	return Get[T](ctx, group, k)
+}
+
+// SetByKey writes data for the key value key, see KeyString.
+func SetByKey[T any, K comparable](ctx context.Context, key K, data T) error {
+	group, k, err := keyParts[T](key)
+	if err != nil {
+		return err
+	}
+	return Set[T](ctx, group, k, data)
+}
+
+// SetByKeyWithExpiration writes data for the key value key with cacheTimeout, see KeyString.
+func SetByKeyWithExpiration[T any, K comparable](ctx context.Context, cacheTimeout time.Duration, key K, data T) error {
+	group, k, err := keyParts[T](key)
+	if err != nil {
+		return err
+	}
+	return SetWithExpiration[T](ctx, cacheTimeout, group, k, data)
+}
+
+// DeleteByKey removes the T stored for the key value key, see KeyString.
+func DeleteByKey[T any, K comparable](ctx context.Context, key K) error {
+	group, k, err := keyParts[T](key)
+	if err != nil {
+		return err
+	}
+	return Delete[T](ctx, group, k)
+}
+
+// FetchByKey is Fetch for the key value key, see KeyString.
+func FetchByKey[T any, K comparable](ctx context.Context, key K, loader func(ctx context.Context) (*T, error), opts ...FetchOption) (*T, error) {
+	group, k, err := keyParts[T](key)
+	if err != nil {
+		return nil, err
+	}
+	return Fetch[T](ctx, group, k, loader, opts...)
+}
+
+// GetSetByKey is FetchByKey for loaders returning a value.
+func GetSetByKey[T any, K comparable](ctx context.Context, key K, loader func(ctx context.Context) (T, error), opts ...FetchOption) (T, error) {
+	return fetchValue(FetchByKey[T](ctx, key, valueLoader(loader), opts...))
+}
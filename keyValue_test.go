@@ -0,0 +1,98 @@
+package ctx_cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+type userKey struct {
+	TenantID string
+	ID       int
+}
+
+type pageKey struct {
+	User  userKey
+	Pages [2]int
+}
+
+type slugKey struct {
+	Slug string
+}
+
+func (k slugKey) CacheKey() string {
+	return "slug/" + k.Slug
+}
+
+func (slugKey) GetName() string {
+	return "slugs"
+}
+
+func TestKeyString(t *testing.T) {
+	for _, tc := range []struct {
+		key  any
+		want string
+	}{
+		{userKey{"a,b", 1}, `TenantID=a\,b,ID=1`},
+		{pageKey{userKey{"t", 2}, [2]int{3, 4}}, `User={TenantID=t,ID=2},Pages=[3,4]`},
+		{slugKey{"x"}, "slug/x"},
+	} {
+		var got string
+		var err error
+		switch k := tc.key.(type) {
+		case userKey:
+			got, err = KeyString(k)
+		case pageKey:
+			got, err = KeyString(k)
+		case slugKey:
+			got, err = KeyString(k)
+		}
+		if err != nil || got != tc.want {
+			t.Fatalf("expected %q, got %q (%v)", tc.want, got, err)
+		}
+	}
+	if k, err := KeyString(42); err != nil || k != "42" {
+		t.Fatalf("expected scalar keys as-is, got %q (%v)", k, err)
+	}
+	if _, err := KeyString(struct{ id int }{1}); err == nil {
+		t.Fatalf("expected unexported fields to be rejected")
+	}
+}
+
+func TestGetSetByKey(t *testing.T) {
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "key_value")
+	ctx := NewClient(c).Context(context.Background())
+
+	calls := 0
+	loader := func(ctx context.Context) (string, error) {
+		calls++
+		return "user", nil
+	}
+	for i := 0; i < 2; i++ {
+		v, err := GetSetByKey(ctx, userKey{"t", 1}, loader, WithTTL(time.Minute))
+		if err != nil || v != "user" {
+			t.Fatalf("expected value, got %q (%v)", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected one loader call, got %d", calls)
+	}
+	if v, err := Get[string](ctx, "", "TenantID=t,ID=1"); err != nil || *v != "user" {
+		t.Fatalf("expected value under the encoded key, got %v (%v)", v, err)
+	}
+
+	if err := SetByKey(ctx, slugKey{"x"}, 7); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := Get[int](ctx, "slugs", "slug/x"); err != nil || *v != 7 {
+		t.Fatalf("expected value under the key group, got %v (%v)", v, err)
+	}
+	if err := DeleteByKey[int](ctx, slugKey{"x"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := GetByKey[int](ctx, slugKey{"x"}); err == nil {
+		t.Fatalf("expected deleted value to miss")
+	}
+}
@@ -0,0 +1,131 @@
+package ctx_cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// MaxKeyLength is the longest key memcache accepts.
+	MaxKeyLength = 250
+
+	keyDelimiter = ":"
+	hashedKeyTag = "#"
+)
+
+// KeyStrategy builds the backend key for a value of typeName stored under group and key.
+type KeyStrategy func(typeName, group, key string) string
+
+var defaultKeys = NewKeyStrategy()
+
+// DefaultKeyStrategy is NewKeyStrategy without options, used unless UseHash is set or a
+// client is given another KeyStrategy.
+func DefaultKeyStrategy(typeName, group, key string) string {
+	return defaultKeys(typeName, group, key)
+}
+
+// ConcatKeyStrategy concatenates the type name, group and key as earlier versions did.
+// Keys are not delimited, so different group and key pairs can collide, only pass it to
+// WithKeyStrategy to keep reading values cached under those keys.
+func ConcatKeyStrategy(typeName, group, key string) string {
+	return typeName + group + key
+}
+
+// HashKeyStrategy stores values under the md5 hash of the default key, used when UseHash
+// is set.
+func HashKeyStrategy(typeName, group, key string) string {
+	return GetMD5Hash(DefaultKeyStrategy(typeName, group, key))
+}
+
+// SHA256KeyHash returns the hex sha256 of key.
+func SHA256KeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// XXKeyHash returns the hex xxhash of key, shorter and faster than SHA256KeyHash.
+func XXKeyHash(key string) string {
+	return strconv.FormatUint(xxhash.Sum64String(key), 16)
+}
+
+type keyStrategyOptions struct {
+	namespace string
+	maxLength int
+	hash      func(key string) string
+	hashAll   bool
+}
+
+type KeyStrategyOption func(*keyStrategyOptions)
+
+// WithKeyNamespace prefixes every key with namespace.
+func WithKeyNamespace(namespace string) KeyStrategyOption {
+	return func(o *keyStrategyOptions) {
+		o.namespace = namespace
+	}
+}
+
+// WithMaxKeyLength hashes keys longer than maxLength, MaxKeyLength by default.
+func WithMaxKeyLength(maxLength int) KeyStrategyOption {
+	return func(o *keyStrategyOptions) {
+		o.maxLength = maxLength
+	}
+}
+
+// WithKeyHash hashes keys with hash, SHA256KeyHash by default.
+func WithKeyHash(hash func(key string) string) KeyStrategyOption {
+	return func(o *keyStrategyOptions) {
+		o.hash = hash
+	}
+}
+
+// WithHashAllKeys hashes every key instead of only the ones that are too long or unsafe.
+func WithHashAllKeys() KeyStrategyOption {
+	return func(o *keyStrategyOptions) {
+		o.hashAll = true
+	}
+}
+
+// NewKeyStrategy returns a KeyStrategy that joins the namespace, type name, group and key
+// with ':' after escaping each part, so distinct parts never produce the same key. Keys
+// that are too long or contain whitespace or control characters are replaced by
+// namespace:#hash, keeping them valid for every backend.
+func NewKeyStrategy(opts ...KeyStrategyOption) KeyStrategy {
+	o := keyStrategyOptions{
+		maxLength: MaxKeyLength,
+		hash:      SHA256KeyHash,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	prefix := ""
+	if o.namespace != "" {
+		prefix = escapeKeyPart(o.namespace) + keyDelimiter
+	}
+	return func(typeName, group, key string) string {
+		k := prefix + escapeKeyPart(typeName) + keyDelimiter + escapeKeyPart(group) + keyDelimiter + escapeKeyPart(key)
+		if o.hashAll || len(k) > o.maxLength || !isSafeKey(k) {
+			return prefix + hashedKeyTag + o.hash(k)
+		}
+		return k
+	}
+}
+
+var keyPartEscaper = strings.NewReplacer(`\`, `\\`, keyDelimiter, `\`+keyDelimiter, hashedKeyTag, `\`+hashedKeyTag)
+
+func escapeKeyPart(part string) string {
+	return keyPartEscaper.Replace(part)
+}
+
+// isSafeKey reports whether k has no whitespace or control characters.
+func isSafeKey(k string) bool {
+	for i := 0; i < len(k); i++ {
+		if k[i] <= ' ' || k[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
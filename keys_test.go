@@ -0,0 +1,63 @@
+package ctx_cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestNewKeyStrategy(t *testing.T) {
+	keys := NewKeyStrategy(WithKeyNamespace("svc"))
+
+	if keys("string", "ab", "c") == keys("string", "a", "bc") {
+		t.Fatalf("expected delimited keys not to collide")
+	}
+	if keys("string", "a:b", "c") == keys("string", "a", "b:c") {
+		t.Fatalf("expected escaped delimiters not to collide")
+	}
+	if k := keys("string", "group", "key"); k != "svc:string:group:key" {
+		t.Fatalf("unexpected key %s", k)
+	}
+
+	long := keys("string", "group", strings.Repeat("k", 300))
+	if len(long) > MaxKeyLength || !strings.HasPrefix(long, "svc:#") {
+		t.Fatalf("expected long key to be hashed under the namespace, got %s", long)
+	}
+	if spaced := keys("string", "group", "key with spaces"); !isSafeKey(spaced) || !strings.HasPrefix(spaced, "svc:#") {
+		t.Fatalf("expected key with spaces to be hashed, got %s", spaced)
+	}
+
+	xx := NewKeyStrategy(WithKeyHash(XXKeyHash), WithHashAllKeys())
+	if k := xx("string", "group", "key"); k != "#"+XXKeyHash("string:group:key") {
+		t.Fatalf("unexpected hashed key %s", k)
+	}
+}
+
+func TestDefaultKeys(t *testing.T) {
+	if GetKey[string]("ab", "c") == GetKey[string]("a", "bc") {
+		t.Fatalf("expected default keys not to collide")
+	}
+	if k := GetKey[string]("group", "key"); k != GetTypeReflect[string]()+":group:key" {
+		t.Fatalf("unexpected key %s", k)
+	}
+
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "default_keys")
+	ctx := ContextWithCache(context.Background(), c)
+	if err := Set[string](ctx, "group", "key", "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetCache(ctx, "group", GetKey[string]("group", "key")); err != nil {
+		t.Fatalf("expected value under the default keys: %v", err)
+	}
+
+	legacy := NewClient(c, WithKeyStrategy(ConcatKeyStrategy))
+	if err := ClientSet[string](ctx, legacy, "group", "key", "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetCache(ctx, "group", GetTypeReflect[string]()+"groupkey"); err != nil {
+		t.Fatalf("expected value under the concatenated key: %v", err)
+	}
+}
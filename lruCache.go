@@ -0,0 +1,286 @@
+package ctx_cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var _ Cache = &LRUCache{}
+var _ BatchCache = &LRUCache{}
+
+// LRUCache is an in-memory cache bounded by entry count and by the total size of keys and
+// values. Once either limit is exceeded the least recently used entries are evicted and
+// counted with the EVICT command.
+type LRUCache struct {
+	mu              sync.Mutex
+	defaultDuration time.Duration
+	maxEntries      int
+	maxBytes        int64
+	size            int64
+	items           map[string]*list.Element
+	order           *list.List
+	cacheTags       CacheTags
+
+	// window and sketch are set by WithTinyLFU. New entries start in window and compete
+	// with the least recently used entry of order once the window is full.
+	window     *list.List
+	windowSize int
+	sketch     *countMinSketch
+}
+
+type LRUCacheOption func(c *LRUCache)
+
+// WithTinyLFU admits entries with W-TinyLFU: new keys enter a small LRU window, and when
+// they leave it they only replace the least recently used entry if they have been
+// requested more often, so one-off keys from scans do not push out hot keys.
+func WithTinyLFU() LRUCacheOption {
+	return func(c *LRUCache) {
+		c.window = list.New()
+		c.windowSize = max(c.maxEntries/100, 1)
+		c.sketch = newCountMinSketch(max(c.maxEntries, 1024))
+	}
+}
+
+type lruEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+	inWindow  bool
+}
+
+func (e *lruEntry) size() int64 {
+	return int64(len(e.key) + len(e.data))
+}
+
+func LRUCacheFlags(prefix string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(prefix+"lrucache", pflag.ExitOnError)
+	fs.Duration(prefix+"lrucache-default-duration", 5*time.Minute, "")
+	fs.Int(prefix+"lrucache-max-entries", 100000, "0 for no limit")
+	fs.Int64(prefix+"lrucache-max-bytes", 256<<20, "0 for no limit")
+	fs.Bool(prefix+"lrucache-tinylfu", false, "admit new keys with W-TinyLFU")
+
+	return fs
+}
+
+func NewLRUCacheFromFlags(prefix string) *LRUCache {
+	var opts []LRUCacheOption
+	if viper.GetBool(prefix + "lrucache-tinylfu") {
+		opts = append(opts, WithTinyLFU())
+	}
+	return NewLRUCache(viper.GetInt(prefix+"lrucache-max-entries"), viper.GetInt64(prefix+"lrucache-max-bytes"), viper.GetDuration(prefix+"lrucache-default-duration"), prefix, opts...)
+}
+
+// NewLRUCache returns a cache holding at most maxEntries entries and maxBytes bytes, a
+// limit of 0 disables it.
+func NewLRUCache(maxEntries int, maxBytes int64, defaultDuration time.Duration, instance string, opts ...LRUCacheOption) *LRUCache {
+	c := &LRUCache{
+		defaultDuration: defaultDuration,
+		maxEntries:      maxEntries,
+		maxBytes:        maxBytes,
+		items:           map[string]*list.Element{},
+		order:           list.New(),
+		cacheTags:       NewCacheTags("lru-cache", instance),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *LRUCache) GetName() string {
+	return fmt.Sprintf("LRUCACHE_%s", c.cacheTags.instance)
+}
+
+func (c *LRUCache) GetParentCaches() map[string]Cache {
+	return map[string]Cache{}
+}
+
+func (c *LRUCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (c *LRUCache) Close() {
+
+}
+
+// Len returns the number of stored entries, including expired ones not yet removed.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Bytes returns the total size of the stored keys and values.
+func (c *LRUCache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *LRUCache) DeleteKey(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	return nil
+}
+
+func (c *LRUCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	data, err := encodeItem(item, cacheTimeout)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(ctx, cacheTimeout, key, data)
+	return nil
+}
+
+func (c *LRUCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return c.SetCacheWithExpiration(ctx, c.defaultDuration, group, key, item)
+}
+
+func (c *LRUCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.get(key)
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return data, nil
+}
+
+func (c *LRUCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	found := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if data, ok := c.get(key); ok {
+			found[key] = data
+		}
+	}
+	return found, nil
+}
+
+func (c *LRUCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	encoded := make(map[string][]byte, len(items))
+	for key, item := range items {
+		data, err := encodeItem(item, cacheTimeout)
+		if err != nil {
+			return err
+		}
+		encoded[key] = data
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, data := range encoded {
+		c.set(ctx, cacheTimeout, key, data)
+	}
+	return nil
+}
+
+func (c *LRUCache) DeleteKeys(ctx context.Context, keys []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		c.remove(key)
+	}
+	return nil
+}
+
+// get returns the value of key and marks it as most recently used. Expired entries are
+// removed. c.mu must be held.
+func (c *LRUCache) get(key string) ([]byte, bool) {
+	if c.sketch != nil {
+		c.sketch.increment(key)
+	}
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+	c.list(e).MoveToFront(el)
+	return e.data, true
+}
+
+// set stores data under key and evicts entries until both limits hold again. Values larger
+// than the byte limit are not stored. c.mu must be held.
+func (c *LRUCache) set(ctx context.Context, cacheTimeout time.Duration, key string, data []byte) {
+	c.remove(key)
+	e := &lruEntry{key: key, data: data}
+	if cacheTimeout > 0 {
+		e.expiresAt = time.Now().Add(cacheTimeout)
+	}
+	if c.maxBytes > 0 && e.size() > c.maxBytes {
+		return
+	}
+	if c.sketch != nil {
+		c.sketch.increment(key)
+		e.inWindow = true
+	}
+	c.items[key] = c.list(e).PushFront(e)
+	c.size += e.size()
+	for c.sketch != nil && c.window.Len() > c.windowSize && !c.full() {
+		c.promote(c.window.Back())
+	}
+	for c.full() {
+		victim := c.order.Back()
+		if c.sketch != nil {
+			if candidate := c.window.Back(); candidate != nil && (victim == nil || c.window.Len() > c.windowSize) {
+				if victim == nil || c.sketch.estimate(candidate.Value.(*lruEntry).key) > c.sketch.estimate(victim.Value.(*lruEntry).key) {
+					c.promote(candidate)
+					continue
+				}
+				victim = candidate
+			}
+		}
+		c.removeElement(victim)
+		c.cacheTags.record(ctx, CacheCmdEVICT, func(err error) CacheStatus {
+			return CacheStatusOK
+		})(nil)
+	}
+}
+
+// full reports whether either limit is exceeded. c.mu must be held.
+func (c *LRUCache) full() bool {
+	return (c.maxEntries > 0 && len(c.items) > c.maxEntries) || (c.maxBytes > 0 && c.size > c.maxBytes)
+}
+
+// promote moves an entry leaving the window to the front of the main list. c.mu must be
+// held.
+func (c *LRUCache) promote(el *list.Element) {
+	e := c.window.Remove(el).(*lruEntry)
+	e.inWindow = false
+	c.items[e.key] = c.order.PushFront(e)
+}
+
+// list returns the list holding e.
+func (c *LRUCache) list(e *lruEntry) *list.List {
+	if e.inWindow {
+		return c.window
+	}
+	return c.order
+}
+
+// remove deletes key if it is stored. c.mu must be held.
+func (c *LRUCache) remove(key string) {
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *LRUCache) removeElement(el *list.Element) {
+	e := el.Value.(*lruEntry)
+	c.list(e).Remove(el)
+	delete(c.items, e.key)
+	c.size -= e.size()
+}
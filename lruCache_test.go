@@ -0,0 +1,76 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(3, 0, time.Minute, "lru")
+	for i := 0; i < 3; i++ {
+		_ = c.SetCache(ctx, "", fmt.Sprint(i), []byte("v"))
+	}
+	if _, err := c.GetCache(ctx, "", "0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = c.SetCache(ctx, "", "3", []byte("v"))
+	if _, err := c.GetCache(ctx, "", "1"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected least recently used key to be evicted, got %v", err)
+	}
+	if _, err := c.GetCache(ctx, "", "0"); err != nil {
+		t.Fatalf("expected recently read key to be kept, got %v", err)
+	}
+	if c.Len() != 3 {
+		t.Fatalf("expected 3 entries, got %d", c.Len())
+	}
+
+	c = NewLRUCache(0, 20, time.Minute, "lru_bytes")
+	_ = c.SetCache(ctx, "", "a", make([]byte, 9))
+	_ = c.SetCache(ctx, "", "b", make([]byte, 9))
+	_ = c.SetCache(ctx, "", "c", make([]byte, 9))
+	if c.Bytes() > 20 || c.Len() != 2 {
+		t.Fatalf("expected byte budget to be kept, got %d bytes in %d entries", c.Bytes(), c.Len())
+	}
+	_ = c.SetCache(ctx, "", "big", make([]byte, 64))
+	if _, err := c.GetCache(ctx, "", "big"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected value over the byte budget not to be stored, got %v", err)
+	}
+
+	_ = c.SetCacheWithExpiration(ctx, time.Millisecond, "", "a", []byte("v"))
+	time.Sleep(5 * time.Millisecond)
+	if _, err := c.GetCache(ctx, "", "a"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected expired key to miss, got %v", err)
+	}
+}
+
+func TestLRUCacheTinyLFU(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(100, 0, time.Minute, "tinylfu", WithTinyLFU())
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprint("hot", i)
+			if _, err := c.GetCache(ctx, "", key); err != nil {
+				_ = c.SetCache(ctx, "", key, []byte("v"))
+			}
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		_ = c.SetCache(ctx, "", fmt.Sprint("scan", i), []byte("v"))
+	}
+	kept := 0
+	for i := 0; i < 50; i++ {
+		if _, err := c.GetCache(ctx, "", fmt.Sprint("hot", i)); err == nil {
+			kept++
+		}
+	}
+	if kept < 45 {
+		t.Fatalf("expected hot keys to survive a scan, kept %d of 50", kept)
+	}
+	if c.Len() != 100 {
+		t.Fatalf("expected 100 entries, got %d", c.Len())
+	}
+}
@@ -2,7 +2,6 @@ package ctx_cache
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -10,9 +9,11 @@ import (
 	"github.com/orijtech/gomemcache/memcache"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"go.uber.org/multierr"
 )
 
 var _ Cache = &MemCache{}
+var _ BatchCache = &MemCache{}
 
 type MemCache struct {
 	memcacheClient  *memcache.Client
@@ -88,7 +89,7 @@ func (c *MemCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time
 	defer func() {
 		s(cacheErr)
 	}()
-	data, err := json.Marshal(item)
+	data, err := encodeItem(item, cacheTimeout)
 	if err != nil {
 		cacheErr = err
 		return err
@@ -130,3 +131,54 @@ func (c *MemCache) GetCache(ctx context.Context, group, key string) ([]byte, err
 	}
 	return it.Value, nil
 }
+
+func (c *MemCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	if !c.enabled {
+		return map[string][]byte{}, nil
+	}
+	var cacheErr error
+	s := c.cacheTags.record(ctx, CacheCmdGET, func(err error) CacheStatus {
+		if err != nil {
+			return CacheStatusERR
+		}
+		return CacheStatusFOUND
+	})
+	defer func() {
+		s(cacheErr)
+	}()
+
+	items, err := c.memcacheClient.GetMulti(ctx, keys)
+	if err != nil {
+		cacheErr = err
+		return nil, err
+	}
+	found := make(map[string][]byte, len(items))
+	for key, it := range items {
+		found[key] = it.Value
+	}
+	return found, nil
+}
+
+func (c *MemCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	if !c.enabled {
+		return nil
+	}
+	var err error
+	for key, item := range items {
+		err = multierr.Combine(err, c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item))
+	}
+	return err
+}
+
+func (c *MemCache) DeleteKeys(ctx context.Context, keys []string) error {
+	if !c.enabled {
+		return nil
+	}
+	var err error
+	for _, key := range keys {
+		if e := c.memcacheClient.Delete(ctx, key); e != nil && !errors.Is(e, memcache.ErrCacheMiss) {
+			err = multierr.Combine(err, e)
+		}
+	}
+	return err
+}
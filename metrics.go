@@ -17,11 +17,18 @@ const (
 	CacheCmdSET    = CacheCmd("SET")
 	CacheCmdGET    = CacheCmd("GET")
 	CacheCmdDELETE = CacheCmd("DELETE")
+	CacheCmdLOAD   = CacheCmd("LOAD")
+	// CacheCmdEVICT is recorded for entries a bounded cache dropped to stay within its limits.
+	CacheCmdEVICT = CacheCmd("EVICT")
 
 	CacheStatusFOUND   = CacheStatus("FOUND")
 	CacheStatusOK      = CacheStatus("OK")
 	CacheStatusMISSING = CacheStatus("MISSING")
 	CacheStatusERR     = CacheStatus("ERR")
+	CacheStatusSTALE   = CacheStatus("STALE")
+	CacheStatusCORRUPT = CacheStatus("CORRUPT")
+	// CacheStatusMISMATCH is recorded for entries written with another layout of the type.
+	CacheStatusMISMATCH = CacheStatus("MISMATCH")
 )
 
 type CacheTags struct {
@@ -31,6 +38,10 @@ type CacheTags struct {
 	Status    tag.Key
 	Cmd       tag.Key
 	Latency   *stats.Int64Measure
+	// PayloadSize and CompressionRatio are recorded for values read and written through
+	// the generic helpers by clients with compression enabled.
+	PayloadSize      *stats.Int64Measure
+	CompressionRatio *stats.Float64Measure
 }
 
 func NewCacheTags(cacheName string, instance string) CacheTags {
@@ -41,6 +52,9 @@ func NewCacheTags(cacheName string, instance string) CacheTags {
 		Status:    tag.MustNewKey(fmt.Sprintf("%s_cache_status", cacheName)),
 		Cmd:       tag.MustNewKey(fmt.Sprintf("%s_cache_cmd", cacheName)),
 		Latency:   stats.Int64(fmt.Sprintf("%s.cache/latency", cacheName), "latency of calls in milliseconds", stats.UnitMilliseconds),
+
+		PayloadSize:      stats.Int64(fmt.Sprintf("%s.cache/payload_size", cacheName), "stored size of cached payloads in bytes", stats.UnitBytes),
+		CompressionRatio: stats.Float64(fmt.Sprintf("%s.cache/compression_ratio", cacheName), "stored size divided by decoded size of cached payloads", stats.UnitDimensionless),
 	}
 	_ = tags.RegisterAllViews()
 	return tags
@@ -101,7 +115,23 @@ func (c *CacheTags) Views() []*view.View {
 		TagKeys:     []tag.Key{c.Cmd, c.Status, c.Name},
 	}
 
-	return []*view.View{latencyView, callsView}
+	payloadSizeView := &view.View{
+		Name:        formatedViewName + "/payload_size",
+		Description: "The distribution of stored payload sizes in bytes",
+		Measure:     c.PayloadSize,
+		Aggregation: view.Distribution(0, 128, 512, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304),
+		TagKeys:     []tag.Key{c.Cmd, c.Status, c.Name},
+	}
+
+	compressionRatioView := &view.View{
+		Name:        formatedViewName + "/compression_ratio",
+		Description: "The distribution of stored to decoded payload size ratios",
+		Measure:     c.CompressionRatio,
+		Aggregation: view.Distribution(0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0),
+		TagKeys:     []tag.Key{c.Cmd, c.Status, c.Name},
+	}
+
+	return []*view.View{latencyView, callsView, payloadSizeView, compressionRatioView}
 }
 
 type Status func(err error) CacheStatus
@@ -120,3 +150,12 @@ func (c *CacheTags) record(ctx context.Context, cmd CacheCmd, status Status) fun
 		_ = stats.RecordWithTags(ctx, tags, c.Latency.M(timeSpentMs))
 	}
 }
+
+func (c *CacheTags) recordPayload(ctx context.Context, cmd CacheCmd, status CacheStatus, size int64, ratio float64) {
+	tags := []tag.Mutator{
+		tag.Insert(c.Name, c.instance),
+		tag.Insert(c.Cmd, string(cmd)),
+		tag.Insert(c.Status, string(status)),
+	}
+	_ = stats.RecordWithTags(ctx, tags, c.PayloadSize.M(size), c.CompressionRatio.M(ratio))
+}
@@ -5,10 +5,30 @@ import (
 	"time"
 )
 
+// GlobalCacheMonitor is the monitor of DefaultClient, read once when it is built.
 var GlobalCacheMonitor CacheMonitor = NewMonitor(time.Minute, false)
 
 const GroupPrefix = "[CTX_CACHE_GROUP]"
 
+const CTX_CACHE_MONITOR = "cache_ctx_monitor"
+
+// ContextWithMonitor makes writes made with the returned context record their group keys
+// in monitor instead of the monitor of the client in ctx.
+func ContextWithMonitor(ctx context.Context, monitor CacheMonitor) context.Context {
+	return context.WithValue(ctx, CTX_CACHE_MONITOR, monitor) //nolint:staticcheck
+}
+
+// GetMonitorFromContext returns the monitor set with ContextWithMonitor, falling back to
+// the monitor of the client in ctx or of DefaultClient.
+func GetMonitorFromContext(ctx context.Context) CacheMonitor {
+	if ctx != nil {
+		if m, ok := ctx.Value(CTX_CACHE_MONITOR).(CacheMonitor); ok && m != nil {
+			return m
+		}
+	}
+	return clientFromContext(ctx).monitor
+}
+
 type CacheMonitor interface {
 	AddGroupKeys(ctx context.Context, group string, newKeys ...string) error
 	HasGroupKeyBeenUpdated(ctx context.Context, group string) bool
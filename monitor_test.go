@@ -19,8 +19,8 @@ type CacheTestMonitor struct {
 
 func TestMonitor(t *testing.T) {
 	ctx := context.Background()
-	GlobalCacheMonitor = NewMonitor(time.Minute, false)
-	go GlobalCacheMonitor.Start(ctx)
+	monitor := NewMonitor(time.Minute, false)
+	go monitor.Start(ctx)
 	workers := 20
 	cacheFunctions := []*CacheTestMonitor{
 		{
@@ -50,7 +50,7 @@ func TestMonitor(t *testing.T) {
 	wg.Add(workers)
 
 	c := NewTieredCache(nil, NewGoCache(cache.New(1*time.Minute, time.Minute), 1*time.Minute, ""), NewMemcache(memcache.New(""), 1*time.Minute, "", false))
-	ctx = ContextWithCache(ctx, c)
+	ctx = NewClient(c, WithClientMonitor(monitor)).Context(ctx)
 	for i := 0; i < workers; i++ {
 		go func() {
 			defer wg.Done()
@@ -68,7 +68,7 @@ func TestMonitor(t *testing.T) {
 				//}
 
 			}
-			_ = GlobalCacheMonitor.DeleteCache(ctx, "default")
+			_ = monitor.DeleteCache(ctx, "default")
 		}()
 	}
 
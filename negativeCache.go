@@ -0,0 +1,90 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type negativeError struct {
+	err error
+	ttl time.Duration
+}
+
+var (
+	negativeErrorsMu sync.RWMutex
+	negativeErrors   = map[string]negativeError{}
+)
+
+// RegisterNegativeError caches err as a tombstone for ttl whenever a GetSet loader returns
+// it, directly or wrapped. Until the tombstone expires Get and GetSet calls for the key
+// return err from the cache without running the loader. Errors are matched by their
+// message across processes, so err should be a sentinel such as sql.ErrNoRows.
+func RegisterNegativeError(err error, ttl time.Duration) {
+	if err == nil || ttl <= 0 {
+		return
+	}
+	negativeErrorsMu.Lock()
+	defer negativeErrorsMu.Unlock()
+	negativeErrors[err.Error()] = negativeError{err: err, ttl: ttl}
+}
+
+// UnregisterNegativeError stops caching err, existing tombstones read as a cache miss.
+func UnregisterNegativeError(err error) {
+	if err == nil {
+		return
+	}
+	negativeErrorsMu.Lock()
+	defer negativeErrorsMu.Unlock()
+	delete(negativeErrors, err.Error())
+}
+
+// matchNegativeError returns the registered sentinel err wraps, if any.
+func matchNegativeError(err error) (negativeError, bool) {
+	if err == nil {
+		return negativeError{}, false
+	}
+	negativeErrorsMu.RLock()
+	defer negativeErrorsMu.RUnlock()
+	for _, n := range negativeErrors {
+		if errors.Is(err, n.err) {
+			return n, true
+		}
+	}
+	return negativeError{}, false
+}
+
+// isNegativeError reports whether err was read from a tombstone and should be returned
+// to the caller instead of running the loader.
+func isNegativeError(err error) bool {
+	_, ok := matchNegativeError(err)
+	return ok
+}
+
+// tombstoneError returns the sentinel stored in a tombstone payload, tombstones for
+// errors that are no longer registered or past their deadline read as a cache miss.
+func tombstoneError(meta entryMeta, payload []byte) error {
+	if meta.IsExpired(time.Now()) {
+		return ErrCacheMiss
+	}
+	negativeErrorsMu.RLock()
+	defer negativeErrorsMu.RUnlock()
+	if n, ok := negativeErrors[string(payload)]; ok {
+		return n.err
+	}
+	return ErrCacheMiss
+}
+
+// cacheLoadError writes a tombstone for group/key when err matches a registered negative
+// error and returns err unchanged.
+func cacheLoadError[T any](ctx context.Context, group, key string, err error) error {
+	n, ok := matchNegativeError(err)
+	if !ok {
+		return err
+	}
+	meta := newEntryMeta(n.ttl).withIntegrity(ctx)
+	meta.Tombstone = true
+	_ = storeEntry(ctx, n.ttl, group, key, cacheKey[T](ctx, group, key), meta.Encode([]byte(n.err.Error())))
+	return err
+}
@@ -0,0 +1,59 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestGetSetNegativeCache(t *testing.T) {
+	errNotFound := errors.New("negative cache: not found")
+	RegisterNegativeError(errNotFound, 50*time.Millisecond)
+	defer UnregisterNegativeError(errNotFound)
+
+	l1 := NewGoCache(cache.New(time.Minute, time.Minute), time.Hour, "neg_l1")
+	l2 := NewGoCache(cache.New(time.Minute, time.Minute), time.Hour, "neg_l2")
+	ctx := NewClient(NewTieredCache(nil, l1, l2)).Context(context.Background())
+
+	var calls atomic.Int64
+	gtr := func(ctx context.Context) (*string, error) {
+		calls.Add(1)
+		return nil, errNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := GetSetP[string](ctx, time.Minute, "neg_group", "neg_key", false, gtr); !errors.Is(err, errNotFound) {
+			t.Fatalf("expected errNotFound, got %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 loader call, got %d", calls.Load())
+	}
+
+	k := GetKey[string]("neg_group", "neg_key")
+	if err := l1.DeleteKey(ctx, k); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Get[string](ctx, "neg_group", "neg_key"); !errors.Is(err, errNotFound) {
+		t.Fatalf("expected backfilled tombstone to return errNotFound, got %v", err)
+	}
+	_, expiration, found := l1.cacher.GetWithExpiration(k)
+	if !found {
+		t.Fatalf("expected l1 to be backfilled")
+	}
+	if time.Until(expiration) > 50*time.Millisecond {
+		t.Fatalf("backfill extended the tombstone ttl to %s", time.Until(expiration))
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if _, err := GetSetP[string](ctx, time.Minute, "neg_group", "neg_key", false, gtr); !errors.Is(err, errNotFound) {
+		t.Fatalf("expected errNotFound, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected expired tombstone to run the loader again, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,211 @@
+package ctx_cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+var _ Cache = &ObjectCache{}
+var _ BatchCache = &ObjectCache{}
+
+// objectStore is implemented by caches that keep values in process without encoding them.
+// Set and Get use it when it is the cache in the context, or the first tier of a
+// TieredCache in the context.
+type objectStore interface {
+	setObject(key string, value interface{}, meta entryMeta, cacheTimeout time.Duration)
+	// getObject returns the entry stored as an object under key. Entries stored as bytes
+	// are not returned and are read through GetCache.
+	getObject(key string) (objectEntry, bool)
+	// objectOnly reports whether setObject alone stores a value. Otherwise the encoded value
+	// is written through the cache first and the object replaces it where objects are kept.
+	objectOnly() bool
+}
+
+type objectEntry struct {
+	value interface{}
+	meta  entryMeta
+}
+
+// lookupObject reads the full cache key k from c when it keeps objects, so values are not
+// taken through the built-in encoding of GetCache. found reports whether c holds an
+// object under k, a value of another type reads as ErrCacheMiss.
+func lookupObject[T any](c GetCache, k string) (v *T, meta entryMeta, found bool, err error) {
+	oc, ok := c.(objectStore)
+	if !ok {
+		return nil, entryMeta{}, false, nil
+	}
+	e, ok := oc.getObject(k)
+	if !ok {
+		return nil, entryMeta{}, false, nil
+	}
+	value, ok := e.value.(T)
+	if !ok {
+		return nil, e.meta, true, ErrCacheMiss
+	}
+	return &value, e.meta, true, nil
+}
+
+// ObjectCache is an in-process cache that stores values as they are passed to Set and
+// returns them to Get of the same type without any encoding. Values are shared between
+// callers unless a copy function is set with WithObjectCopy. As the first tier of a
+// TieredCache it keeps objects while the other tiers receive encoded values.
+type ObjectCache struct {
+	defaultDuration time.Duration
+	cacher          *cache.Cache
+	cacheTags       CacheTags
+	clone           func(v interface{}) interface{}
+}
+
+type ObjectCacheOption func(c *ObjectCache)
+
+// WithObjectCopy copies values when they are stored and when they are returned, so mutable
+// values such as maps, slices and pointers are not shared with the cache. clone is called
+// with every stored value, including the monitor's own entries, and returns it unchanged
+// for types that need no copy.
+func WithObjectCopy(clone func(v interface{}) interface{}) ObjectCacheOption {
+	return func(c *ObjectCache) {
+		c.clone = clone
+	}
+}
+
+func NewObjectCache(cacher *cache.Cache, defaultDuration time.Duration, instance string, opts ...ObjectCacheOption) *ObjectCache {
+	c := &ObjectCache{
+		cacher:          cacher,
+		defaultDuration: defaultDuration,
+		cacheTags:       NewCacheTags("object-cache", instance),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *ObjectCache) GetName() string {
+	return fmt.Sprintf("OBJECTCACHE_%s", c.cacheTags.instance)
+}
+
+func (c *ObjectCache) GetParentCaches() map[string]Cache {
+	return map[string]Cache{}
+}
+
+func (c *ObjectCache) DeleteKey(ctx context.Context, key string) error {
+	c.cacher.Delete(key)
+	return nil
+}
+
+func (c *ObjectCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (c *ObjectCache) Close() {
+
+}
+
+func (c *ObjectCache) setObject(key string, value interface{}, meta entryMeta, cacheTimeout time.Duration) {
+	if c.clone != nil {
+		value = c.clone(value)
+	}
+	if cacheTimeout == 0 {
+		cacheTimeout = c.defaultDuration
+	}
+	c.cacher.Set(key, objectEntry{value: value, meta: meta}, cacheTimeout)
+}
+
+func (c *ObjectCache) objectOnly() bool {
+	return true
+}
+
+func (c *ObjectCache) getObject(key string) (objectEntry, bool) {
+	data, found := c.cacher.Get(key)
+	if !found {
+		return objectEntry{}, false
+	}
+	e, ok := data.(objectEntry)
+	if !ok {
+		return objectEntry{}, false
+	}
+	if c.clone != nil {
+		e.value = c.clone(e.value)
+	}
+	return e, true
+}
+
+// SetCacheWithExpiration stores bytes as-is, since the generic helpers already encoded
+// them, and any other item as an object.
+func (c *ObjectCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	if b, ok := item.([]byte); ok {
+		c.cacher.Set(key, b, cacheTimeout)
+		return nil
+	}
+	c.setObject(key, item, newEntryMeta(cacheTimeout), cacheTimeout)
+	return nil
+}
+
+func (c *ObjectCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return c.SetCacheWithExpiration(ctx, c.defaultDuration, group, key, item)
+}
+
+// GetCache returns the entry envelope for key. Objects are encoded on demand with the
+// built-in encoding and the integrity of the client in ctx, for example when a tiered
+// cache backfills another tier. The generic helpers read objects without encoding them,
+// so clients with another codec still find them.
+func (c *ObjectCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	data, found := c.cacher.Get(key)
+	if !found {
+		return nil, ErrCacheMiss
+	}
+	return c.encode(ctx, data)
+}
+
+func (c *ObjectCache) encode(ctx context.Context, data interface{}) ([]byte, error) {
+	e, ok := data.(objectEntry)
+	if !ok {
+		return data.([]byte), nil
+	}
+	payload, err := encodeBuiltin(e.value)
+	if err != nil {
+		return nil, err
+	}
+	meta := e.meta.withIntegrity(ctx)
+	meta.Codec = CodecBuiltin
+	if t := reflect.TypeOf(e.value); t != nil {
+		meta.TypeHash = typeHashOf(t)
+	}
+	return meta.Encode(payload), nil
+}
+
+func (c *ObjectCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	found := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		data, ok := c.cacher.Get(key)
+		if !ok {
+			continue
+		}
+		v, err := c.encode(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		found[key] = v
+	}
+	return found, nil
+}
+
+func (c *ObjectCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	for key, item := range items {
+		if err := c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ObjectCache) DeleteKeys(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		c.cacher.Delete(key)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+type objectUser struct {
+	Name  string
+	Roles map[string]bool
+}
+
+func TestObjectCache(t *testing.T) {
+	c := NewObjectCache(cache.New(time.Minute, time.Minute), time.Minute, "object")
+	ctx := NewClient(c).Context(context.Background())
+
+	u := &objectUser{Name: "a", Roles: map[string]bool{"admin": true}}
+	if err := SetWithExpiration[*objectUser](ctx, time.Minute, "users", "a", u); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := Get[*objectUser](ctx, "users", "a")
+	if err != nil || *v != u {
+		t.Fatalf("expected the stored pointer back, got %v (%v)", v, err)
+	}
+	if _, err := Get[string](ctx, "users", "a"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected another type to read as a miss, got %v", err)
+	}
+
+	data, err := c.GetCache(ctx, "users", GetKey[*objectUser]("users", "a"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, _, err := decodeStoredValue[*objectUser](nil, data)
+	if err != nil || (*decoded).Name != "a" {
+		t.Fatalf("expected object to be encoded on demand, got %v (%v)", decoded, err)
+	}
+
+	if err := c.SetCache(ctx, "raw", "raw", []byte("bytes")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw, err := c.GetCache(ctx, "raw", "raw"); err != nil || string(raw) != "bytes" {
+		t.Fatalf("expected bytes to be stored as-is, got %q (%v)", raw, err)
+	}
+}
+
+func TestObjectCacheCopy(t *testing.T) {
+	c := NewObjectCache(cache.New(time.Minute, time.Minute), time.Minute, "object_copy", WithObjectCopy(func(v interface{}) interface{} {
+		if u, ok := v.(objectUser); ok {
+			u.Roles = maps.Clone(u.Roles)
+			return u
+		}
+		return v
+	}))
+	ctx := NewClient(c).Context(context.Background())
+
+	u := objectUser{Name: "a", Roles: map[string]bool{"admin": true}}
+	if err := Set[objectUser](ctx, "users", "a", u); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u.Roles["owner"] = true
+
+	v, err := Get[objectUser](ctx, "users", "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v.Roles["guest"] = true
+	v, _ = Get[objectUser](ctx, "users", "a")
+	if len(v.Roles) != 1 {
+		t.Fatalf("expected cached value to be isolated from callers, got %v", v.Roles)
+	}
+}
+
+func TestObjectCacheTiered(t *testing.T) {
+	l1 := NewObjectCache(cache.New(time.Minute, time.Minute), time.Minute, "object_l1")
+	l2 := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "object_l2")
+	ctx := NewClient(NewTieredCache(nil, l1, l2)).Context(context.Background())
+
+	u := &objectUser{Name: "a"}
+	if err := SetWithExpiration[*objectUser](ctx, time.Minute, "users", "a", u); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := Get[*objectUser](ctx, "users", "a")
+	if err != nil || *v != u {
+		t.Fatalf("expected the stored pointer back from the first tier, got %v (%v)", v, err)
+	}
+
+	k := GetKey[*objectUser]("users", "a")
+	data, err := l2.GetCache(ctx, "users", k)
+	if err != nil {
+		t.Fatalf("expected the encoded value in the second tier, got %v", err)
+	}
+	decoded, _, err := decodeStoredValue[*objectUser](nil, data)
+	if err != nil || (*decoded).Name != "a" {
+		t.Fatalf("expected the second tier to decode, got %v (%v)", decoded, err)
+	}
+
+	_ = l1.DeleteKey(ctx, k)
+	v, err = Get[*objectUser](ctx, "users", "a")
+	if err != nil || (*v).Name != "a" {
+		t.Fatalf("expected the value from the second tier, got %v (%v)", v, err)
+	}
+}
+
+func TestObjectCacheClients(t *testing.T) {
+	for name, opt := range map[string]ClientOption{
+		"checksum": WithChecksum(),
+		"hmac":     WithHMAC([]byte("secret")),
+		"json":     WithCodec(JSONCodec),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewObjectCache(cache.New(time.Minute, time.Minute), time.Minute, "object_"+name)
+			ctx := NewClient(c, opt).Context(context.Background())
+
+			if err := SetWithExpiration[objectUser](ctx, time.Minute, "users", "a", objectUser{Name: "a"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			found, misses, err := GetMany[objectUser](ctx, "users", []string{"a", "b"})
+			if err != nil || found["a"] == nil || found["a"].Name != "a" || len(misses) != 1 || misses[0] != "b" {
+				t.Fatalf("expected a from the batch read and b as a miss, got %v %v (%v)", found, misses, err)
+			}
+			if v, err := GetFromCache[objectUser](ctx, c, "users", "a"); err != nil || v.Name != "a" {
+				t.Fatalf("expected a from the given cache, got %v (%v)", v, err)
+			}
+			if v, err := Get[objectUser](ctx, "users", "a"); err != nil || v.Name != "a" {
+				t.Fatalf("expected a to be kept after the batch read, got %v (%v)", v, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package ctx_cache
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Policy declares the defaults used for every cached value of a type, see RegisterType.
+type Policy struct {
+	// TTL is used when a call passes no cache timeout.
+	TTL time.Duration
+	// Codec encodes the type unless the client sets a type codec for it.
+	Codec Codec
+	// KeyPrefix is prepended to every key of the type.
+	KeyPrefix string
+	// Group returns the group used when a call passes an empty group. Batch calls pass an
+	// empty key. Without it types implementing CacheObject are grouped by GetName.
+	Group func(key string) string
+	// Schema folds a fingerprint of the field names, types and tags of the type into every
+	// stored entry. Entries written with another layout, or before Schema was set, read as
+	// misses and are recorded with the MISMATCH status.
+	Schema bool
+	// Jitter shortens every TTL by a random fraction of up to Jitter, so values written
+	// together do not all expire together.
+	Jitter float64
+}
+
+var (
+	policiesMu sync.RWMutex
+	policies   = map[string]Policy{}
+)
+
+// RegisterType sets the policy for T, replacing any previous one.
+func RegisterType[T any](p Policy) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	policies[GetTypeReflect[T]()] = p
+}
+
+// UnregisterType removes the policy for T. Values already stored under a key prefix are
+// no longer found.
+func UnregisterType[T any]() {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	delete(policies, GetTypeReflect[T]())
+}
+
+func policyFor[T any]() (Policy, bool) {
+	return policyForName(GetTypeReflect[T]())
+}
+
+// policyForName returns the policy registered for the type GetTypeReflect names name.
+func policyForName(name string) (Policy, bool) {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	p, ok := policies[name]
+	return p, ok
+}
+
+// policyTTL returns cacheTimeout, or the TTL registered for T when it is zero, with the
+// registered jitter applied.
+func policyTTL[T any](cacheTimeout time.Duration) time.Duration {
+	p, ok := policyFor[T]()
+	if !ok {
+		return cacheTimeout
+	}
+	if cacheTimeout == 0 {
+		cacheTimeout = p.TTL
+	}
+	if p.Jitter > 0 && cacheTimeout > 0 {
+		cacheTimeout -= time.Duration(rand.Float64() * min(p.Jitter, 1) * float64(cacheTimeout))
+	}
+	return cacheTimeout
+}
+
+// policyGroup returns group, or the group registered for T when it is empty.
+func policyGroup[T any](group, key string) string {
+	if group != "" {
+		return group
+	}
+	if p, ok := policyFor[T](); ok && p.Group != nil {
+		return p.Group(key)
+	}
+	return objectGroup[T]()
+}
+
+// objectGroup returns the name of T when T or *T implements CacheObject.
+func objectGroup[T any]() string {
+	if o, ok := any(new(T)).(CacheObject); ok {
+		return o.GetName()
+	}
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Pointer {
+		if o, ok := reflect.New(t.Elem()).Interface().(CacheObject); ok {
+			return o.GetName()
+		}
+	}
+	return ""
+}
+
+// policyKey prepends the key prefix registered for T.
+func policyKey[T any](key string) string {
+	if p, ok := policyFor[T](); ok {
+		return p.KeyPrefix + key
+	}
+	return key
+}
+
+// policyCodec returns the codec registered for T, nil when there is none.
+func policyCodec[T any]() Codec {
+	if p, ok := policyFor[T](); ok {
+		return p.Codec
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package ctx_cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+type policyUser struct {
+	Name string
+}
+
+type policyAccount struct {
+	ID int
+}
+
+func (policyAccount) GetName() string {
+	return "accounts"
+}
+
+func TestRegisterType(t *testing.T) {
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "policy")
+	ctx := NewClient(c).Context(context.Background())
+
+	RegisterType[policyUser](Policy{
+		TTL:       time.Hour,
+		Codec:     JSONCodec,
+		KeyPrefix: "v2:",
+		Group:     func(key string) string { return "users" },
+		Jitter:    0.1,
+	})
+	defer UnregisterType[policyUser]()
+
+	if err := Set[policyUser](ctx, "", "a", policyUser{Name: "a"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := c.GetCache(ctx, "users", GetKey[policyUser]("users", "a"))
+	if err != nil {
+		t.Fatalf("expected value under the registered group and prefix: %v", err)
+	}
+	v, meta, err := decodeStoredValue[policyUser](JSONCodec, data)
+	if err != nil || v.Name != "a" {
+		t.Fatalf("expected value encoded with the registered codec, got %v (%v)", v, err)
+	}
+	if ttl := time.Until(meta.ExpiresAt); ttl > time.Hour || ttl < 50*time.Minute {
+		t.Fatalf("expected the registered ttl with jitter, got %s", ttl)
+	}
+	if v, err := Get[policyUser](ctx, "", "a"); err != nil || v.Name != "a" {
+		t.Fatalf("expected value, got %v (%v)", v, err)
+	}
+
+	if err := SetWithExpiration[policyAccount](ctx, 0, "", "1", policyAccount{ID: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetCache(ctx, "accounts", GetKey[policyAccount]("accounts", "1")); err != nil {
+		t.Fatalf("expected value under the CacheObject group: %v", err)
+	}
+}
+
+func TestPolicyJitterAppliedOnce(t *testing.T) {
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "policy_jitter")
+	ctx := NewClient(c).Context(context.Background())
+
+	RegisterType[policyUser](Policy{TTL: time.Hour, Jitter: 0.5})
+	defer UnregisterType[policyUser]()
+
+	for i := 0; i < 200; i++ {
+		key := strconv.Itoa(i)
+		if err := SetFromCache[policyUser](ctx, c, "users", key, policyUser{Name: key}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := c.GetCache(ctx, "users", GetKey[policyUser]("users", key))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		meta, _, err := decodeEntryMeta(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ttl := time.Until(meta.ExpiresAt); ttl > time.Hour || ttl < 30*time.Minute-time.Second {
+			t.Fatalf("expected a ttl between 30m and 1h, got %s", ttl)
+		}
+	}
+}
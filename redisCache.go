@@ -2,7 +2,6 @@ package ctx_cache
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/Seann-Moser/go-serve/pkg/ctxLogger"
@@ -15,6 +14,7 @@ import (
 )
 
 var _ Cache = (*RedisCache)(nil)
+var _ BatchCache = (*RedisCache)(nil)
 
 type RedisCache struct {
 	cacher          *redis.Client
@@ -84,7 +84,7 @@ func (c *RedisCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout ti
 	if item == nil {
 		return nil
 	}
-	data, err := json.Marshal(item)
+	data, err := encodeItem(item, cacheTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to marshal item: %w", err)
 	}
@@ -115,3 +115,56 @@ func (c *RedisCache) Ping(ctx context.Context) error {
 	}
 	return nil
 }
+
+func (c *RedisCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	found := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return found, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	values, err := c.cacher.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %d keys: %w", len(keys), err)
+	}
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			found[keys[i]] = []byte(s)
+		}
+	}
+	return found, nil
+}
+
+func (c *RedisCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	if !c.enabled || len(items) == 0 {
+		return nil
+	}
+	pipe := c.cacher.Pipeline()
+	for key, item := range items {
+		if item == nil {
+			continue
+		}
+		data, err := encodeItem(item, cacheTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to marshal item: %w", err)
+		}
+		pipe.Set(ctx, key, data, cacheTimeout)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisCache) DeleteKeys(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	stat, err := c.cacher.Del(ctx, keys...).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete %d keys: %w", len(keys), err)
+	}
+	if stat != 0 {
+		ctxLogger.Debug(ctx, "deleted redis cache keys", zap.Int64("deleted", stat))
+	}
+	return nil
+}
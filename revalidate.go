@@ -0,0 +1,94 @@
+package ctx_cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Seann-Moser/go-serve/pkg/ctxLogger"
+	"go.uber.org/zap"
+)
+
+const (
+	CTX_CACHE_STALE_WHILE_REVALIDATE = "cache_ctx_stale_while_revalidate"
+)
+
+var revalidating sync.Map
+
+// ContextWithStaleWhileRevalidate makes GetSet calls write entries with a soft deadline of
+// softTimeout next to their hard cacheTimeout. Between the two the cached value is returned
+// immediately while a single background refresh reloads and rewrites it.
+func ContextWithStaleWhileRevalidate(ctx context.Context, softTimeout time.Duration) context.Context {
+	return context.WithValue(ctx, CTX_CACHE_STALE_WHILE_REVALIDATE, softTimeout) //nolint:staticcheck
+}
+
+func staleWhileRevalidate(ctx context.Context) time.Duration {
+	softTimeout, _ := ctx.Value(CTX_CACHE_STALE_WHILE_REVALIDATE).(time.Duration)
+	return softTimeout
+}
+
+// setLoaded writes a value returned by a GetSet loader using the soft timeout and stale
+// grace window from ctx, recording how long the loader took to compute it.
+func setLoaded[T any](ctx context.Context, cacheTimeout, computed time.Duration, group, key string, data T) error {
+	return setEntry[T](ctx, loadedMeta(ctx, cacheTimeout, computed), cacheTimeout, group, key, data)
+}
+
+func loadedMeta(ctx context.Context, cacheTimeout, computed time.Duration) entryMeta {
+	return newEntryMeta(cacheTimeout).
+		WithSoftTimeout(staleWhileRevalidate(ctx)).
+		WithStaleTimeout(staleIfError(ctx)).
+		WithComputeDuration(computed)
+}
+
+// getRevalidate reads group/key like Get. When the entry is past its soft deadline it is
+// still returned and load is started in the background, at most once per key. An entry
+// past its hard deadline reads as a miss and is returned as stale when ctx allows serving
+// it on loader errors. With early expiration enabled a live entry may also read as a miss
+// shortly before its hard deadline.
+func getRevalidate[T, R any](ctx context.Context, group, key string, load func(ctx context.Context) (R, error)) (v *T, stale *T, err error) {
+	v, meta, err := lookupEntry[T](ctx, group, key)
+	if err != nil || v == nil {
+		return nil, nil, err
+	}
+	n := time.Now()
+	if meta.IsExpired(n) {
+		if staleIfError(ctx) > 0 && meta.IsServable(n) {
+			stale = v
+		}
+		return nil, stale, ErrCacheMiss
+	}
+	if meta.IsEarlyExpired(n, earlyExpiration(ctx)) {
+		if staleIfError(ctx) > 0 {
+			stale = v
+		}
+		return nil, stale, ErrCacheMiss
+	}
+	if meta.IsStale(n) {
+		refreshCtx := context.WithoutCancel(ctx)
+		if staleWhileRevalidate(refreshCtx) == 0 {
+			refreshCtx = ContextWithStaleWhileRevalidate(refreshCtx, meta.SoftTimeout())
+		}
+		revalidate[R](refreshCtx, cacheKey[T](ctx, group, key), load)
+	}
+	return v, nil, nil
+}
+
+// revalidate runs load for key in the background unless a refresh of the same key in the
+// same cache is already running. A failed refresh is logged and recorded, the stale entry
+// stays in place until its hard deadline.
+func revalidate[R any](ctx context.Context, key string, load func(ctx context.Context) (R, error)) {
+	running := flightKey[R](ctx, key)
+	if _, ok := revalidating.LoadOrStore(running, struct{}{}); ok {
+		return
+	}
+	go func() {
+		defer revalidating.Delete(running)
+		recordLoad := loaderTags.record(ctx, CacheCmdLOAD, func(err error) CacheStatus {
+			return CacheStatusERR
+		})
+		if _, err := loadOnce[R](ctx, key, load); err != nil {
+			ctxLogger.Warn(ctx, "background cache refresh failed", zap.String("key", key), zap.Error(err))
+			recordLoad(err)
+		}
+	}()
+}
@@ -0,0 +1,166 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestGetSetStaleWhileRevalidate(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "swr")).Context(context.Background())
+	ctx = ContextWithStaleWhileRevalidate(ctx, 50*time.Millisecond)
+
+	var calls atomic.Int64
+	gtr := func(ctx context.Context) (int64, error) {
+		return calls.Add(1), nil
+	}
+
+	v, err := GetSet[int64](ctx, time.Minute, "swr_group", "swr_key", false, gtr)
+	if err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d (%v)", v, err)
+	}
+	time.Sleep(80 * time.Millisecond)
+
+	v, err = GetSet[int64](ctx, time.Minute, "swr_group", "swr_key", false, gtr)
+	if err != nil || v != 1 {
+		t.Fatalf("expected stale value 1, got %d (%v)", v, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	v, err = GetSet[int64](ctx, time.Minute, "swr_group", "swr_key", false, gtr)
+	if err != nil || v != 2 {
+		t.Fatalf("expected refreshed value 2, got %d (%v)", v, err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 loader calls, got %d", calls.Load())
+	}
+}
+
+func TestEntryMetaSurvivesTieredBackfill(t *testing.T) {
+	l1 := NewGoCache(cache.New(time.Minute, time.Minute), time.Hour, "l1")
+	l2 := NewGoCache(cache.New(time.Minute, time.Minute), time.Hour, "l2")
+	ctx := NewClient(NewTieredCache(nil, l1, l2)).Context(context.Background())
+	ctx = ContextWithStaleWhileRevalidate(ctx, 30*time.Second)
+
+	_, err := GetSet[string](ctx, time.Minute, "swr_group", "tiered_key", false, func(ctx context.Context) (string, error) {
+		return "value", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	k := GetKey[string]("swr_group", "tiered_key")
+	if err := l1.DeleteKey(ctx, k); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v, meta, err := getEntry[string](ctx, "swr_group", "tiered_key")
+	if err != nil || *v != "value" {
+		t.Fatalf("expected value, got %v (%v)", v, err)
+	}
+	if meta.SoftTimeout() != 30*time.Second {
+		t.Fatalf("expected soft timeout to survive, got %s", meta.SoftTimeout())
+	}
+
+	_, expiration, found := l1.cacher.GetWithExpiration(k)
+	if !found {
+		t.Fatalf("expected l1 to be backfilled")
+	}
+	if time.Until(expiration) > time.Minute {
+		t.Fatalf("backfill extended the hard ttl to %s", time.Until(expiration))
+	}
+}
+
+func TestGetSetStaleWhileRevalidatePerCache(t *testing.T) {
+	newCtx := func(name string) context.Context {
+		ctx := ContextWithCache(context.Background(), NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, name))
+		return ContextWithStaleWhileRevalidate(ctx, 10*time.Millisecond)
+	}
+	ctxA, ctxB := newCtx("swr_a"), newCtx("swr_b")
+	for _, ctx := range []context.Context{ctxA, ctxB} {
+		if _, err := GetSet[string](ctx, time.Minute, "swr_group", "shared_key", false, func(ctx context.Context) (string, error) {
+			return "seed", nil
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	if _, err := GetSet[string](ctxA, time.Minute, "swr_group", "shared_key", false, func(ctx context.Context) (string, error) {
+		close(entered)
+		<-release
+		return "a", nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-entered
+
+	refreshed := make(chan struct{})
+	if _, err := GetSet[string](ctxB, time.Minute, "swr_group", "shared_key", false, func(ctx context.Context) (string, error) {
+		close(refreshed)
+		return "b", nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatalf("refresh of the second cache was skipped while the first was running")
+	}
+
+	close(release)
+	// Wait for both refreshes to be written so they do not outlive the test.
+	for ctx, want := range map[context.Context]string{ctxA: "a", ctxB: "b"} {
+		deadline := time.Now().Add(time.Second)
+		for v, _ := Get[string](ctx, "swr_group", "shared_key"); v == nil || *v != want; v, _ = Get[string](ctx, "swr_group", "shared_key") {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected refreshed value %q, got %v", want, v)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestGetSetStaleWhileRevalidateRefreshError(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "swr_err")).Context(context.Background())
+	ctx = ContextWithStaleWhileRevalidate(ctx, 10*time.Millisecond)
+
+	if _, err := GetSet[string](ctx, time.Minute, "swr_group", "err_key", false, func(ctx context.Context) (string, error) {
+		return "seed", nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		failed := make(chan struct{})
+		v, err := GetSet[string](ctx, time.Minute, "swr_group", "err_key", false, func(ctx context.Context) (string, error) {
+			defer close(failed)
+			return "", errors.New("refresh failed")
+		})
+		if err != nil || v != "seed" {
+			t.Fatalf("expected stale value seed, got %q (%v)", v, err)
+		}
+		select {
+		case <-failed:
+		case <-time.After(time.Second):
+			t.Fatalf("expected the background refresh to run after a failed one")
+		}
+		// Wait for the refresh to finish before the next stale read starts another.
+		deadline := time.Now().Add(time.Second)
+		for _, running := revalidating.Load(flightKey[*string](ctx, GetKey[string]("swr_group", "err_key"))); running; _, running = revalidating.Load(flightKey[*string](ctx, GetKey[string]("swr_group", "err_key"))) {
+			if time.Now().After(deadline) {
+				t.Fatalf("background refresh did not finish")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
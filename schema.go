@@ -0,0 +1,83 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+var (
+	// errSchemaMismatch is returned for entries written with another layout of a type whose
+	// Policy sets Schema.
+	errSchemaMismatch = fmt.Errorf("%w: schema mismatch", ErrCacheMiss)
+
+	schemas    sync.Map
+	schemaTags = NewCacheTags("schema", "ctx_cache")
+)
+
+// typeHashOf fingerprints t so values read back as another type are treated as a miss.
+// Types whose Policy sets Schema also fold in their layout.
+func typeHashOf(t reflect.Type) uint64 {
+	name := reflect.PointerTo(t).String()
+	if p, ok := policyForName(name); ok && p.Schema {
+		return xxhash.Sum64String(name + "\x00" + schemaOf(t))
+	}
+	return xxhash.Sum64String(name)
+}
+
+// schemaOf describes the field names, types and tags of t and every type it contains,
+// computed once per type.
+func schemaOf(t reflect.Type) string {
+	if s, ok := schemas.Load(t); ok {
+		return s.(string)
+	}
+	var b strings.Builder
+	writeSchema(&b, t, map[reflect.Type]bool{})
+	s := b.String()
+	schemas.Store(t, s)
+	return s
+}
+
+func writeSchema(b *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	b.WriteString(t.String())
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		b.WriteByte('(')
+		writeSchema(b, t.Elem(), seen)
+		b.WriteByte(')')
+	case reflect.Map:
+		b.WriteByte('(')
+		writeSchema(b, t.Key(), seen)
+		b.WriteByte(',')
+		writeSchema(b, t.Elem(), seen)
+		b.WriteByte(')')
+	case reflect.Struct:
+		b.WriteByte('{')
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fmt.Fprintf(b, "%s %q ", f.Name, f.Tag)
+			writeSchema(b, f.Type, seen)
+			b.WriteByte(';')
+		}
+		b.WriteByte('}')
+	}
+}
+
+// recordSchemaMismatch counts reads that found an entry written with another layout.
+func recordSchemaMismatch(ctx context.Context, err error) {
+	if !errors.Is(err, errSchemaMismatch) {
+		return
+	}
+	schemaTags.record(ctx, CacheCmdGET, func(err error) CacheStatus {
+		return CacheStatusMISMATCH
+	})(err)
+}
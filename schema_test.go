@@ -0,0 +1,71 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/patrickmn/go-cache"
+)
+
+type schemaUser struct {
+	Name string `json:"name"`
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	a := reflect.TypeOf(struct {
+		A int `json:"a"`
+	}{})
+	b := reflect.TypeOf(struct {
+		A int `json:"b"`
+	}{})
+	c := reflect.TypeOf(struct {
+		A int64 `json:"a"`
+	}{})
+	if schemaOf(a) == schemaOf(b) || schemaOf(a) == schemaOf(c) {
+		t.Fatalf("expected tags and field types to change the schema")
+	}
+	type node struct {
+		Next     *node
+		Children []node
+	}
+	if schemaOf(reflect.TypeOf(node{})) == "" {
+		t.Fatalf("expected recursive types to have a schema")
+	}
+}
+
+func TestSchemaMismatch(t *testing.T) {
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "schema")
+	ctx := NewClient(c).Context(context.Background())
+	k := GetKey[schemaUser]("schema_group", "key")
+
+	if err := SetWithExpiration[schemaUser](ctx, time.Minute, "schema_group", "key", schemaUser{Name: "a"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	RegisterType[schemaUser](Policy{Schema: true})
+	defer UnregisterType[schemaUser]()
+	if _, err := Get[schemaUser](ctx, "schema_group", "key"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected entry written without a schema to miss, got %v", err)
+	}
+
+	if err := SetWithExpiration[schemaUser](ctx, time.Minute, "schema_group", "key", schemaUser{Name: "b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := Get[schemaUser](ctx, "schema_group", "key"); err != nil || v.Name != "b" {
+		t.Fatalf("expected value, got %v (%v)", v, err)
+	}
+
+	data, _ := c.GetCache(ctx, "schema_group", k)
+	meta, payload, err := decodeEntryMeta(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	meta.TypeHash = xxhash.Sum64String(GetTypeReflect[schemaUser]() + "\x00old layout")
+	_ = c.SetCache(ctx, "schema_group", k, meta.Encode(payload))
+	if _, _, err := lookupEntry[schemaUser](ctx, "schema_group", "key"); !errors.Is(err, errSchemaMismatch) {
+		t.Fatalf("expected entry written with an older layout to be a schema mismatch, got %v", err)
+	}
+}
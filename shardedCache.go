@@ -0,0 +1,199 @@
+package ctx_cache
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var _ Cache = &ShardedCache{}
+var _ BatchCache = &ShardedCache{}
+
+// ShardedCache is an in-memory cache that splits keys by hash across shards, each with its
+// own lock and expiry sweeper, so concurrent callers rarely contend on the same lock.
+type ShardedCache struct {
+	defaultDuration time.Duration
+	shards          []*cacheShard
+	mask            uint64
+	stop            chan struct{}
+	closeOnce       sync.Once
+	cacheTags       CacheTags
+}
+
+type cacheShard struct {
+	mu    sync.RWMutex
+	items map[string]shardEntry
+}
+
+type shardEntry struct {
+	data      []byte
+	expiresAt int64
+}
+
+func (e shardEntry) expired(now int64) bool {
+	return e.expiresAt > 0 && now > e.expiresAt
+}
+
+func ShardedCacheFlags(prefix string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(prefix+"shardedcache", pflag.ExitOnError)
+	fs.Int(prefix+"shardedcache-shards", 0, "0 for 4 shards per CPU")
+	fs.Duration(prefix+"shardedcache-default-duration", 5*time.Minute, "")
+	fs.Duration(prefix+"shardedcache-cleanup-duration", 1*time.Minute, "")
+
+	return fs
+}
+
+func NewShardedCacheFromFlags(prefix string) *ShardedCache {
+	return NewShardedCache(viper.GetInt(prefix+"shardedcache-shards"), viper.GetDuration(prefix+"shardedcache-default-duration"), viper.GetDuration(prefix+"shardedcache-cleanup-duration"), prefix)
+}
+
+// NewShardedCache returns a cache with shards rounded up to a power of two, 4 per CPU when
+// shards is 0. Every shard removes its expired entries each cleanupInterval until Close is
+// called, a cleanupInterval of 0 leaves them until they are read.
+func NewShardedCache(shards int, defaultDuration, cleanupInterval time.Duration, instance string) *ShardedCache {
+	if shards <= 0 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	shards = 1 << bits.Len(uint(shards-1))
+	c := &ShardedCache{
+		defaultDuration: defaultDuration,
+		shards:          make([]*cacheShard, shards),
+		mask:            uint64(shards - 1),
+		stop:            make(chan struct{}),
+		cacheTags:       NewCacheTags("sharded-cache", instance),
+	}
+	for i := range c.shards {
+		c.shards[i] = &cacheShard{items: map[string]shardEntry{}}
+		if cleanupInterval > 0 {
+			go c.shards[i].sweep(cleanupInterval, c.stop)
+		}
+	}
+	return c
+}
+
+func (c *ShardedCache) GetName() string {
+	return fmt.Sprintf("SHARDEDCACHE_%s", c.cacheTags.instance)
+}
+
+func (c *ShardedCache) GetParentCaches() map[string]Cache {
+	return map[string]Cache{}
+}
+
+func (c *ShardedCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close stops the expiry sweepers.
+func (c *ShardedCache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+func (c *ShardedCache) shard(key string) *cacheShard {
+	return c.shards[xxhash.Sum64String(key)&c.mask]
+}
+
+func (c *ShardedCache) DeleteKey(ctx context.Context, key string) error {
+	s := c.shard(key)
+	s.mu.Lock()
+	delete(s.items, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (c *ShardedCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	data, err := encodeItem(item, cacheTimeout)
+	if err != nil {
+		return err
+	}
+	c.shard(key).set(key, data, cacheTimeout)
+	return nil
+}
+
+func (c *ShardedCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return c.SetCacheWithExpiration(ctx, c.defaultDuration, group, key, item)
+}
+
+func (c *ShardedCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	data, ok := c.shard(key).get(key, time.Now().UnixNano())
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return data, nil
+}
+
+func (c *ShardedCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	now := time.Now().UnixNano()
+	found := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if data, ok := c.shard(key).get(key, now); ok {
+			found[key] = data
+		}
+	}
+	return found, nil
+}
+
+func (c *ShardedCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	for key, item := range items {
+		if err := c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ShardedCache) DeleteKeys(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		_ = c.DeleteKey(ctx, key)
+	}
+	return nil
+}
+
+func (s *cacheShard) get(key string, now int64) ([]byte, bool) {
+	s.mu.RLock()
+	e, ok := s.items[key]
+	s.mu.RUnlock()
+	if !ok || e.expired(now) {
+		return nil, false
+	}
+	return e.data, true
+}
+
+func (s *cacheShard) set(key string, data []byte, cacheTimeout time.Duration) {
+	e := shardEntry{data: data}
+	if cacheTimeout > 0 {
+		e.expiresAt = time.Now().Add(cacheTimeout).UnixNano()
+	}
+	s.mu.Lock()
+	s.items[key] = e
+	s.mu.Unlock()
+}
+
+// sweep removes expired entries every interval until stop is closed.
+func (s *cacheShard) sweep(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			now := time.Now().UnixNano()
+			s.mu.Lock()
+			for key, e := range s.items {
+				if e.expired(now) {
+					delete(s.items, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
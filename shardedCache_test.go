@@ -0,0 +1,48 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewShardedCache(3, time.Minute, time.Millisecond, "sharded")
+	defer c.Close()
+	if len(c.shards) != 4 {
+		t.Fatalf("expected shards to be rounded up to 4, got %d", len(c.shards))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprint(i, "_", j)
+				_ = c.SetCache(ctx, "", key, []byte(key))
+				if v, err := c.GetCache(ctx, "", key); err != nil || string(v) != key {
+					t.Errorf("expected %s, got %q (%v)", key, v, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_ = c.SetCacheWithExpiration(ctx, time.Millisecond, "", "short", []byte("v"))
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c.GetCache(ctx, "", "short"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected expired key to miss, got %v", err)
+	}
+	s := c.shard("short")
+	s.mu.RLock()
+	_, ok := s.items["short"]
+	s.mu.RUnlock()
+	if ok {
+		t.Fatalf("expected sweeper to remove expired key")
+	}
+}
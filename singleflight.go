@@ -0,0 +1,66 @@
+package ctx_cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	CTX_CACHE_NO_SINGLE_FLIGHT = "cache_ctx_no_single_flight"
+)
+
+var loaderGroup singleflight.Group
+
+// ContextWithoutSingleFlight disables collapsing of concurrent cache misses for
+// GetSet calls made with the returned context, every caller runs its own loader.
+func ContextWithoutSingleFlight(ctx context.Context) context.Context {
+	return context.WithValue(ctx, CTX_CACHE_NO_SINGLE_FLIGHT, true) //nolint:staticcheck
+}
+
+func singleFlightEnabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(CTX_CACHE_NO_SINGLE_FLIGHT).(bool)
+	return !disabled
+}
+
+// flightKey identifies a load of key as T from the cache in ctx. Callers using different
+// cache instances, directly or through different clients, never share a load.
+func flightKey[T any](ctx context.Context, key string) string {
+	return cacheIdentity(GetCacheFromContext(ctx)) + ":" + GetTypeReflect[T]() + key
+}
+
+// cacheIdentity returns the address of c, or its type and value when c is not a pointer.
+func cacheIdentity(c Cache) string {
+	if v := reflect.ValueOf(c); v.Kind() == reflect.Pointer {
+		return strconv.FormatUint(uint64(v.Pointer()), 16)
+	}
+	return fmt.Sprintf("%T:%v", c, c)
+}
+
+// loadOnce runs fn once for all concurrent callers sharing the same key, result type and
+// cache. The loader runs detached from the first caller's cancellation so one caller
+// giving up does not fail the others, while each waiter still returns as soon as its
+// own context ends.
+func loadOnce[T any](ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	if !singleFlightEnabled(ctx) {
+		return fn(ctx)
+	}
+	loadCtx := context.WithoutCancel(ctx)
+	ch := loaderGroup.DoChan(flightKey[T](ctx, key), func() (interface{}, error) {
+		return fn(loadCtx)
+	})
+	select {
+	case <-ctx.Done():
+		var tmp T
+		return tmp, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			var tmp T
+			return tmp, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
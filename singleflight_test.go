@@ -0,0 +1,193 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestGetSetSingleFlight(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "single-flight")).Context(context.Background())
+
+	var calls atomic.Int64
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	gtr := func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			close(entered)
+		}
+		<-release
+		return "loaded", nil
+	}
+
+	workers := 20
+	calling := sync.WaitGroup{}
+	calling.Add(workers)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			calling.Done()
+			v, err := GetSet[string](ctx, time.Minute, "sf_group", "sf_key", false, gtr)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if v != "loaded" {
+				t.Errorf("expected loaded, got %s", v)
+			}
+		}()
+	}
+	<-entered
+	waitForCallers(&calling)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 loader call, got %d", calls.Load())
+	}
+}
+
+func TestGetSetSingleFlightSharedError(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "single-flight")).Context(context.Background())
+
+	loadErr := errors.New("load failed")
+	var calls atomic.Int64
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	gtr := func(ctx context.Context) (*string, error) {
+		if calls.Add(1) == 1 {
+			close(entered)
+		}
+		<-release
+		return nil, loadErr
+	}
+
+	workers := 10
+	calling := sync.WaitGroup{}
+	calling.Add(workers)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			calling.Done()
+			_, err := GetSetP[string](ctx, time.Minute, "sf_group", "sf_err_key", false, gtr)
+			if !errors.Is(err, loadErr) {
+				t.Errorf("expected %v, got %v", loadErr, err)
+			}
+		}()
+	}
+	<-entered
+	waitForCallers(&calling)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 loader call, got %d", calls.Load())
+	}
+}
+
+func TestGetSetSingleFlightWaiterCancel(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "single-flight")).Context(context.Background())
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	defer func() {
+		close(release)
+		<-done
+	}()
+	entered := make(chan struct{})
+	gtr := func(ctx context.Context) (int, error) {
+		close(entered)
+		<-release
+		return 1, nil
+	}
+	go func() {
+		defer close(done)
+		_, _ = GetSet[int](ctx, time.Minute, "sf_group", "sf_cancel_key", false, gtr)
+	}()
+	<-entered
+
+	waiterCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err := GetSet[int](waiterCtx, time.Minute, "sf_group", "sf_cancel_key", false, gtr)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestGetSetWithoutSingleFlight(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "single-flight")).Context(context.Background())
+	ctx = ContextWithoutSingleFlight(ctx)
+
+	var calls atomic.Int64
+	started := sync.WaitGroup{}
+	workers := 5
+	started.Add(workers)
+	gtr := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		started.Done()
+		started.Wait()
+		return 7, nil
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			_, _ = GetSet[int](ctx, time.Minute, "sf_group", "sf_opt_out_key", false, gtr)
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != int64(workers) {
+		t.Fatalf("expected %d loader calls, got %d", workers, calls.Load())
+	}
+}
+
+func TestGetSetSingleFlightPerCache(t *testing.T) {
+	a := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "single-flight-a"))
+	b := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "single-flight-b"))
+	ctx := context.Background()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = ClientFetch[string](ctx, a, "sf_group", "sf_cache_key", func(ctx context.Context) (*string, error) {
+			close(entered)
+			<-release
+			v := "a"
+			return &v, nil
+		})
+	}()
+	<-entered
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	v, err := ClientFetch[string](ctx, b, "sf_group", "sf_cache_key", func(ctx context.Context) (*string, error) {
+		v := "b"
+		return &v, nil
+	})
+	if err != nil || *v != "b" {
+		t.Fatalf("expected the other client's load not to be shared, got %v (%v)", v, err)
+	}
+	if v, err := ClientGet[string](ctx, b, "sf_group", "sf_cache_key"); err != nil || *v != "b" {
+		t.Fatalf("expected the value in the other client's cache, got %v (%v)", v, err)
+	}
+}
+
+// waitForCallers waits until every caller has started its call, then gives them time to
+// join the flight that is holding the loader.
+func waitForCallers(calling *sync.WaitGroup) {
+	calling.Wait()
+	time.Sleep(50 * time.Millisecond)
+}
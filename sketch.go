@@ -0,0 +1,63 @@
+package ctx_cache
+
+import (
+	"math/bits"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const sketchDepth = 4
+
+// countMinSketch estimates how often keys were seen with 4-bit counters. Counters are
+// halved after every 10 increments per counter of width, so old popularity fades.
+type countMinSketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint32
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(width int) *countMinSketch {
+	width = 1 << bits.Len(uint(width-1))
+	s := &countMinSketch{mask: uint32(width - 1), resetAt: 10 * width}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index returns the counter of key in row i, derived from the two halves of its hash.
+func (s *countMinSketch) index(h uint64, i int) uint32 {
+	return (uint32(h) + uint32(i)*uint32(h>>32)) & s.mask
+}
+
+func (s *countMinSketch) increment(key string) {
+	h := xxhash.Sum64String(key)
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < 15 {
+			*c++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch) estimate(key string) uint8 {
+	h := xxhash.Sum64String(key)
+	m := uint8(15)
+	for i := range s.rows {
+		m = min(m, s.rows[i][s.index(h, i)])
+	}
+	return m
+}
+
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,45 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Seann-Moser/go-serve/pkg/ctxLogger"
+	"go.uber.org/zap"
+)
+
+const (
+	CTX_CACHE_STALE_IF_ERROR = "cache_ctx_stale_if_error"
+)
+
+// ErrStaleValue is returned together with the last good value when the loader failed and
+// the value was served from the stale-if-error grace window.
+var ErrStaleValue = errors.New("cache served stale value")
+
+var loaderTags = NewCacheTags("loader", "ctx_cache")
+
+// ContextWithStaleIfError keeps entries written by GetSet calls for grace past their hard
+// deadline. When the loader errors or times out, the expired value is returned with
+// ErrStaleValue instead of the loader error, which is logged and recorded.
+func ContextWithStaleIfError(ctx context.Context, grace time.Duration) context.Context {
+	return context.WithValue(ctx, CTX_CACHE_STALE_IF_ERROR, grace) //nolint:staticcheck
+}
+
+func staleIfError(ctx context.Context) time.Duration {
+	grace, _ := ctx.Value(CTX_CACHE_STALE_IF_ERROR).(time.Duration)
+	return grace
+}
+
+// serveStale falls back to stale after a failed load, returning err unchanged when there
+// is nothing to serve or the loader returned a registered negative error.
+func serveStale[T any](ctx context.Context, group, key string, stale *T, err error) (*T, error) {
+	if stale == nil || err == nil || staleIfError(ctx) <= 0 || isNegativeError(err) {
+		return nil, err
+	}
+	ctxLogger.Warn(ctx, "serving stale cache value after loader error", zap.String("group", group), zap.String("key", key), zap.Error(err))
+	loaderTags.record(ctx, CacheCmdLOAD, func(err error) CacheStatus {
+		return CacheStatusSTALE
+	})(err)
+	return stale, ErrStaleValue
+}
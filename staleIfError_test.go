@@ -0,0 +1,48 @@
+package ctx_cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestGetSetStaleIfError(t *testing.T) {
+	ctx := NewClient(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "sie")).Context(context.Background())
+	ctx = ContextWithStaleIfError(ctx, time.Minute)
+
+	loadErr := errors.New("database down")
+	fail := false
+	gtr := func(ctx context.Context) (*string, error) {
+		if fail {
+			return nil, loadErr
+		}
+		v := "good"
+		return &v, nil
+	}
+
+	v, err := GetSetCheckP[string](ctx, 50*time.Millisecond, "sie_group", "sie_key", false, func(ctx context.Context, data *string) bool { return true }, gtr)
+	if err != nil || *v != "good" {
+		t.Fatalf("expected good, got %v (%v)", v, err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if _, err := Get[string](ctx, "sie_group", "sie_key"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected expired entry to read as a miss, got %v", err)
+	}
+
+	fail = true
+	v, err = GetSetCheckP[string](ctx, 50*time.Millisecond, "sie_group", "sie_key", false, func(ctx context.Context, data *string) bool { return true }, gtr)
+	if !errors.Is(err, ErrStaleValue) {
+		t.Fatalf("expected ErrStaleValue, got %v", err)
+	}
+	if v == nil || *v != "good" {
+		t.Fatalf("expected stale value good, got %v", v)
+	}
+
+	_, err = GetSetCheckP[string](ContextWithCache(context.Background(), GetCacheFromContext(ctx)), 50*time.Millisecond, "sie_group", "sie_key", false, func(ctx context.Context, data *string) bool { return true }, gtr)
+	if !errors.Is(err, loadErr) {
+		t.Fatalf("expected loader error without stale-if-error, got %v", err)
+	}
+}
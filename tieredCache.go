@@ -11,6 +11,8 @@ import (
 )
 
 var _ Cache = &TieredCache{}
+var _ BatchCache = &TieredCache{}
+var _ objectStore = &TieredCache{}
 
 type TieredCache struct {
 	cachePool []Cache
@@ -35,6 +37,34 @@ func NewTieredCache(setter GetCache, cacheList ...Cache) Cache {
 	}
 }
 
+// objects returns the first tier when it keeps values as objects.
+func (t *TieredCache) objects() (objectStore, bool) {
+	if len(t.cachePool) == 0 {
+		return nil, false
+	}
+	oc, ok := t.cachePool[0].(objectStore)
+	return oc, ok
+}
+
+// setObject keeps value unencoded in the first tier when it is an ObjectCache, the other
+// tiers receive the encoded value written before it.
+func (t *TieredCache) setObject(key string, value interface{}, meta entryMeta, cacheTimeout time.Duration) {
+	if oc, ok := t.objects(); ok {
+		oc.setObject(key, value, meta, cacheTimeout)
+	}
+}
+
+func (t *TieredCache) getObject(key string) (objectEntry, bool) {
+	if oc, ok := t.objects(); ok {
+		return oc.getObject(key)
+	}
+	return objectEntry{}, false
+}
+
+func (t *TieredCache) objectOnly() bool {
+	return false
+}
+
 func (t *TieredCache) GetName() string {
 	pool := []string{}
 	for _, cache := range t.cachePool {
@@ -109,7 +139,17 @@ func (t *TieredCache) GetCache(ctx context.Context, group, key string) ([]byte,
 	var v []byte
 	var err error
 	defer func() {
+		if v == nil {
+			return
+		}
+		ttl, hasTTL := entryTTL(v)
 		for _, c := range missedCacheList {
+			if hasTTL {
+				if ttl > 0 {
+					_ = c.SetCacheWithExpiration(ctx, ttl, group, key, v)
+				}
+				continue
+			}
 			_ = c.SetCache(ctx, group, key, v)
 		}
 	}()
@@ -131,3 +171,103 @@ func (t *TieredCache) GetCache(ctx context.Context, group, key string) ([]byte,
 	}
 	return v, nil
 }
+
+// GetCacheMany reads keys tier by tier, asking each tier only for the keys the tiers
+// before it missed, then backfills every tier with just the keys it was missing.
+func (t *TieredCache) GetCacheMany(ctx context.Context, group string, keys []string) (map[string][]byte, error) {
+	found := make(map[string][]byte, len(keys))
+	missed := make([][]string, 0, len(t.cachePool))
+	remaining := keys
+	for _, c := range t.cachePool {
+		if len(remaining) == 0 {
+			break
+		}
+		// a failing tier is treated like a miss, as in GetCache
+		v, _ := getCacheMany(ctx, c, group, remaining)
+		var next []string
+		for _, key := range remaining {
+			if d, ok := v[key]; ok {
+				found[key] = d
+				continue
+			}
+			next = append(next, key)
+		}
+		missed = append(missed, next)
+		remaining = next
+	}
+	if len(remaining) > 0 && t.getter != nil {
+		v, err := getCacheMany(ctx, t.getter, group, remaining)
+		if err != nil {
+			return nil, err
+		}
+		for key, d := range v {
+			found[key] = d
+		}
+	}
+	for i, keys := range missed {
+		t.backfill(ctx, t.cachePool[i], group, keys, found)
+	}
+	return found, nil
+}
+
+// backfill copies the found values for keys into c without extending their ttl.
+func (t *TieredCache) backfill(ctx context.Context, c Cache, group string, keys []string, found map[string][]byte) {
+	byTTL := map[time.Duration]map[string]interface{}{}
+	for _, key := range keys {
+		v, ok := found[key]
+		if !ok {
+			continue
+		}
+		ttl, hasTTL := entryTTL(v)
+		if !hasTTL {
+			_ = c.SetCache(ctx, group, key, v)
+			continue
+		}
+		if ttl <= 0 {
+			continue
+		}
+		// group by whole seconds so keys share a batch, rounding down never extends the ttl
+		if ttl > time.Second {
+			ttl = ttl.Truncate(time.Second)
+		}
+		if byTTL[ttl] == nil {
+			byTTL[ttl] = map[string]interface{}{}
+		}
+		byTTL[ttl][key] = v
+	}
+	for ttl, items := range byTTL {
+		_ = setCacheMany(ctx, c, ttl, group, items)
+	}
+}
+
+func (t *TieredCache) SetCacheManyWithExpiration(ctx context.Context, cacheTimeout time.Duration, group string, items map[string]interface{}) error {
+	var err error
+	var success bool
+	for _, c := range t.cachePool {
+		if e := setCacheMany(ctx, c, cacheTimeout, group, items); e == nil {
+			success = true
+		} else {
+			err = multierr.Combine(err, e)
+		}
+	}
+	if success {
+		return nil
+	}
+	return err
+}
+
+func (t *TieredCache) DeleteKeys(ctx context.Context, keys []string) error {
+	var err error
+	var success bool
+	for _, c := range t.cachePool {
+		if e := deleteKeys(ctx, c, keys); e == nil {
+			success = true
+		} else {
+			err = multierr.Combine(err, e)
+		}
+	}
+	if success {
+		return nil
+	}
+	return err
+}